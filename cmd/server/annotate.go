@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// annotateHandler extracts the uploaded invoice like extractHandler, but responds
+// with a copy of the PDF that has a summary page of the extracted fields appended.
+func (app *api) annotateHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := app.extractOptions(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !app.hasDiskSpace(w, r) {
+		return
+	}
+
+	pdf, filename, ok := app.readUpload(w, r)
+	if !ok || !app.requirePDF(w, r, filename, pdf) {
+		return
+	}
+
+	if !app.acquireSlotOrFail(w, r) {
+		return
+	}
+	defer app.releaseSlot()
+
+	details, err := timedExtraction(app.metrics, func() (*extractor.InvoiceDetails, error) {
+		return extractor.ExtractDetailsContext(r.Context(), bytes.NewReader(pdf), opts)
+	})
+	if err != nil {
+		app.extractionFailed(w, r, err, filename)
+		return
+	}
+
+	annotated, err := extractor.Annotate(pdf, details)
+	if err != nil {
+		app.log(r.Context()).Error("annotation failed", "error", err, "filename", filename)
+		app.errorResponse(w, r, http.StatusInternalServerError, "failed to annotate PDF")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(annotated)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(annotated); err != nil {
+		app.log(r.Context()).Error("failed to write annotated pdf", "error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAPIKey is a middleware that rejects requests lacking a valid API key,
+// sent as "Authorization: Bearer <key>" or in the X-API-Key header.
+// When no keys are configured, authentication is disabled and every request passes.
+func (app *api) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(app.config.apiKeyHashes) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestAPIKey(r)
+		if key == "" || !app.validAPIKey(key) {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="simpleinvoice"`)
+			w.Header().Add("WWW-Authenticate", `APIKey realm="simpleinvoice"`)
+			app.errorResponse(w, r, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIKey returns the API key r carries, taken from a bearer token in
+// the Authorization header or else from X-API-Key, or "" when it has none.
+func requestAPIKey(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// validAPIKey reports whether key hashes to one of the configured digests.
+// Every digest is compared in constant time to avoid leaking which one is closest.
+func (app *api) validAPIKey(key string) bool {
+	sum := sha256.Sum256([]byte(key))
+	valid := 0
+	for _, h := range app.config.apiKeyHashes {
+		valid |= subtle.ConstantTimeCompare(sum[:], h[:])
+	}
+	return valid == 1
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sync"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+const (
+	// maxBatchUploadSize bounds the combined size of the files in one batch.
+	maxBatchUploadSize = 100 << 20 // 100MB
+	// maxBatchFiles bounds the number of files in one batch.
+	maxBatchFiles = 500
+)
+
+// batchResult is the outcome of extracting one file of a batch.
+type batchResult struct {
+	Filename string                    `json:"filename"`
+	Details  *extractor.InvoiceDetails `json:"details,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+}
+
+// currencyTotals sums the amounts of the successful extractions in one currency.
+type currencyTotals struct {
+	Invoices    int     `json:"invoices"`
+	TotalAmount float64 `json:"total_amount"`
+	TaxAmount   float64 `json:"tax_amount"`
+}
+
+// batchAggregate summarises a whole batch. Failed files are counted but not summed.
+type batchAggregate struct {
+	Succeeded  int                       `json:"succeeded"`
+	Failed     int                       `json:"failed"`
+	ByCurrency map[string]currencyTotals `json:"by_currency"`
+}
+
+// unknownCurrency keys the totals of invoices that state no currency.
+const unknownCurrency = "UNKNOWN"
+
+// batchHandler extracts every file of the upload field of a multipart form and responds with
+// one result per file, in upload order. Files are extracted concurrently, each
+// holding an extraction slot, and a file that fails only fails its own entry.
+// With ?aggregate=true the response is an object holding the results and the
+// per-currency sums.
+func (app *api) batchHandler(w http.ResponseWriter, r *http.Request) {
+	aggregate, err := queryBool(r.URL.Query(), "aggregate")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	files, opts, ok := app.readBatch(w, r)
+	if !ok {
+		return
+	}
+
+	results := make([]batchResult, len(files))
+	app.extractBatch(r, files, opts, func(i int, res batchResult) {
+		results[i] = res
+	})
+
+	var payload any = results
+	if aggregate {
+		payload = map[string]any{"results": results, "aggregate": aggregateResults(results)}
+	}
+	if err := app.writeJSON(w, http.StatusOK, payload, nil); err != nil {
+		app.log(r.Context()).Error("failed to write batch response", "error", err)
+	}
+}
+
+// readBatch reads the extraction options and the uploaded files of a batch
+// request. On failure it writes the error response itself and reports false.
+func (app *api) readBatch(w http.ResponseWriter, r *http.Request) ([]*multipart.FileHeader, extractor.Options, bool) {
+	opts, err := app.extractOptions(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return nil, opts, false
+	}
+	if !app.hasDiskSpace(w, r) {
+		return nil, opts, false
+	}
+
+	if !app.parseMultipartForm(w, r, maxBatchUploadSize) {
+		return nil, opts, false
+	}
+	files := app.uploadedFiles(r)
+	if len(files) == 0 {
+		app.errorResponse(w, r, http.StatusBadRequest, app.missingUploadMessage(r))
+		return nil, opts, false
+	}
+
+	if len(files) > maxBatchFiles {
+		app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("a batch may hold at most %d files, got %d", maxBatchFiles, len(files)))
+		return nil, opts, false
+	}
+	return files, opts, true
+}
+
+// extractBatch extracts the files of a batch, calling done with the index and
+// result of each file as it completes. A fixed set of workers, no larger than
+// the extraction limit, takes the files in turn, so a large batch never floods
+// the wait queue. done is called from the workers, concurrently.
+func (app *api) extractBatch(r *http.Request, files []*multipart.FileHeader, opts extractor.Options, done func(int, batchResult)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(len(files), max(app.config.maxConcurrent, 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				done(i, app.extractBatchFile(r, files[i], opts))
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// extractBatchFile extracts one file of a batch while holding an extraction
+// slot. Failures, including a panic in the extractor, only fail its entry.
+func (app *api) extractBatchFile(r *http.Request, fh *multipart.FileHeader, opts extractor.Options) (res batchResult) {
+	res.Filename = fh.Filename
+	defer func() {
+		if p := recover(); p != nil {
+			app.log(r.Context()).Error("extraction panicked", "panic", fmt.Sprint(p), "filename", fh.Filename)
+			res.Details, res.Error = nil, "failed to extract details from PDF"
+		}
+	}()
+
+	file, err := fh.Open()
+	if err != nil {
+		res.Error = "could not read the uploaded file"
+		return res
+	}
+	pdf, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		res.Error = "could not read the uploaded file"
+		return res
+	}
+	if !extractor.IsPDF(pdf) {
+		res.Error = "the uploaded file is not a PDF"
+		return res
+	}
+
+	if err := app.acquireSlot(r.Context()); err != nil {
+		res.Error = "not extracted: " + err.Error()
+		return res
+	}
+	defer app.releaseSlot()
+
+	details, err := timedExtraction(app.metrics, func() (*extractor.InvoiceDetails, error) {
+		return extractor.ExtractDetailsContext(r.Context(), bytes.NewReader(pdf), opts)
+	})
+	var ambiguity *extractor.AmbiguityError
+	if errors.As(err, &ambiguity) {
+		res.Error = ambiguity.Error()
+		return res
+	}
+	if err != nil {
+		_, res.Error = app.extractionError(r.Context(), err, fh.Filename)
+		return res
+	}
+	if !details.Extracted && app.config.rejectEmpty {
+		res.Error = errNoFieldsExtracted
+		return res
+	}
+	res.Details = details
+	return res
+}
+
+// aggregateResults counts the outcomes of a batch and sums the successful
+// extractions per currency.
+func aggregateResults(results []batchResult) batchAggregate {
+	agg := batchAggregate{ByCurrency: make(map[string]currencyTotals)}
+	for _, res := range results {
+		if res.Details == nil {
+			agg.Failed++
+			continue
+		}
+		agg.Succeeded++
+
+		currency := res.Details.Currency
+		if currency == "" {
+			currency = unknownCurrency
+		}
+		totals := agg.ByCurrency[currency]
+		totals.Invoices++
+		totals.TotalAmount += res.Details.TotalAmountValue
+		totals.TaxAmount += res.Details.TaxAmountValue
+		agg.ByCurrency[currency] = totals
+	}
+	return agg
+}
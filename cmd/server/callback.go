@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Delivery of job results to callback URLs. A failed attempt is retried after
+// callbackBackoff, doubling each time, when the receiver answers 5xx or cannot
+// be reached; any other answer ends the delivery.
+const (
+	callbackTimeout = 10 * time.Second
+	callbackBackoff = time.Second
+	// maxCallbackAttempts caps SIMPLEINVOICE_CALLBACK_ATTEMPTS.
+	maxCallbackAttempts = 10
+)
+
+// callbackSignatureHeader carries the signature of a callback body, as
+// "sha256=" and the hex HMAC-SHA256 of the body keyed with
+// SIMPLEINVOICE_CALLBACK_SECRET, so receivers can check that it came from
+// this server.
+const callbackSignatureHeader = "X-SimpleInvoice-Signature"
+
+// errBlockedAddress is returned for callbacks to addresses of the server's own
+// network, which a client could otherwise reach through it.
+var errBlockedAddress = errors.New("callback address is not publicly routable")
+
+// blockedCallbackIP reports whether ip is loopback, private, link-local,
+// unspecified or multicast, none of which callbacks may reach.
+func blockedCallbackIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// newCallbackClient returns the client that posts job results. Unless
+// allowPrivate is set, it refuses to connect to the addresses of
+// blockedCallbackIP; the check runs on the resolved address at dial time, so
+// a public name that resolves to an internal address is refused too. It
+// dials directly, ignoring proxy settings, and does not follow redirects, so
+// a result only ever goes to the URL the client gave.
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: callbackTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blockedCallbackIP(addr.Addr()) {
+				return fmt.Errorf("%w: %s", errBlockedAddress, addr.Addr())
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: callbackTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: callbackTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// parseCallbackURL validates the callback_url of an asynchronous extraction.
+// It must be an absolute https URL, or http as well when
+// SIMPLEINVOICE_CALLBACK_ALLOW_HTTP is set for local development. A host that
+// is a blocked IP address is refused here already; names are checked once
+// resolved, by the callback client.
+func (app *api) parseCallbackURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", errors.New("callback_url must be an absolute URL")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && app.config.callbackAllowHTTP:
+	default:
+		return "", errors.New("callback_url must use https")
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !app.config.callbackAllowPrivate && blockedCallbackIP(ip) {
+		return "", errors.New("callback_url must not point to a loopback, private or link-local address")
+	}
+	return u.String(), nil
+}
+
+// deliverCallback posts the outcome of j to its callback URL, retrying with
+// exponential backoff up to config.callbackAttempts times. ctx only carries
+// the logger of the request that queued the job.
+func (app *api) deliverCallback(ctx context.Context, j job) {
+	body, err := json.Marshal(j)
+	if err != nil {
+		app.log(ctx).Error("failed to encode job callback", "job", j.ID, "error", err)
+		return
+	}
+
+	backoff := callbackBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := app.postCallback(j.callbackURL, body)
+		if err == nil {
+			app.log(ctx).Info("delivered job callback", "job", j.ID, "attempts", attempt)
+			return
+		}
+		if !retry || attempt >= app.config.callbackAttempts {
+			app.log(ctx).Warn("job callback failed", "job", j.ID, "attempts", attempt, "error", err)
+			return
+		}
+		app.log(ctx).Debug("retrying job callback", "job", j.ID, "attempt", attempt, "error", err, "backoff", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postCallback makes one delivery attempt, reporting whether a failure is
+// worth retrying. The body is signed when a callback secret is configured.
+func (app *api) postCallback(callbackURL string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := app.config.callbackSecret; secret != "" {
+		req.Header.Set(callbackSignatureHeader, signCallback(secret, body))
+	}
+
+	resp, err := app.callbacks.Do(req)
+	if err != nil {
+		return !errors.Is(err, errBlockedAddress), err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver answered %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return false, nil
+}
+
+// signCallback returns the value of callbackSignatureHeader for body.
+func signCallback(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestBlockedCallbackIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"224.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+		{"203.0.113.7", false},
+		{"2001:db8::1", false},
+		{"8.8.8.8", false},
+	}
+	for _, tt := range tests {
+		if got := blockedCallbackIP(netip.MustParseAddr(tt.ip)); got != tt.blocked {
+			t.Errorf("blockedCallbackIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestParseCallbackURL(t *testing.T) {
+	app := &api{config: config{callbackAllowHTTP: true}}
+	for _, raw := range []string{
+		"http://127.0.0.1:8080/hook",
+		"https://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+	} {
+		if _, err := app.parseCallbackURL(raw); err == nil {
+			t.Errorf("parseCallbackURL(%q) accepted an internal address", raw)
+		}
+	}
+	if _, err := app.parseCallbackURL("https://example.com/hook"); err != nil {
+		t.Errorf("parseCallbackURL rejected a public host: %v", err)
+	}
+
+	app.config.callbackAllowPrivate = true
+	if _, err := app.parseCallbackURL("http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("parseCallbackURL rejected loopback with private addresses allowed: %v", err)
+	}
+}
+
+// The dial-time check catches what the URL check cannot, such as a name
+// resolving to loopback, and such failures are not retried.
+func TestPostCallbackRefusesLoopback(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hit = true }))
+	defer srv.Close()
+
+	app := &api{callbacks: newCallbackClient(false)}
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	retry, err := app.postCallback(url, []byte(`{}`))
+	if !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("postCallback error = %v, want errBlockedAddress", err)
+	}
+	if retry {
+		t.Error("a blocked delivery was marked for retry")
+	}
+	if hit {
+		t.Error("the blocked receiver was reached")
+	}
+}
+
+func TestPostCallbackSignsBody(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"id":"abc","status":"done"}`)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(read)
+		got = r.Header.Get(callbackSignatureHeader)
+		if got != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	app := &api{
+		config:    config{callbackSecret: secret},
+		callbacks: newCallbackClient(true),
+	}
+	if _, err := app.postCallback(srv.URL, body); err != nil {
+		t.Fatalf("postCallback: %v (signature %q)", err, got)
+	}
+
+	app.config.callbackSecret = ""
+	if _, err := app.postCallback(srv.URL, body); err == nil {
+		t.Error("an unsigned callback passed the receiver's check")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// runExtract implements the extract subcommand: it extracts the details of a
+// PDF on disk, or read from stdin when the path is "-", and prints them as
+// JSON to stdout, without starting the server. It is configured by the same
+// SIMPLEINVOICE_* variables as the server; logs go to stderr. It returns the
+// process exit code: 0 on success, 1 when the extraction fails and 2 for
+// invalid usage or configuration.
+func runExtract(args []string) int {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s extract [flags] <file.pdf | ->\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	compact := fs.Bool("compact", false, "print the JSON on a single line")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	path := fs.Arg(0)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		return 2
+	}
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.logLevel})
+	extractor.SetLogger(slog.New(newRedactingHandler(handler, cfg.logRedactions)))
+	if err := extractor.Configure(cfg.extractor); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid extractor configuration:", err)
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	details, err := extractor.ExtractDetails(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if !*compact {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(details); err != nil {
+		fmt.Fprintln(os.Stderr, "writing result:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// envPrefix namespaces every environment variable read by the server.
+const envPrefix = "SIMPLEINVOICE_"
+
+// config holds the runtime settings of the server, resolved once at startup.
+type config struct {
+	addr              string
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxConnections    int // Open connections accepted at once; 0 means unlimited.
+
+	maxConcurrent int     // Size of the extraction semaphore.
+	maxQueue      int     // Requests allowed to wait for a semaphore slot; 0 means unlimited.
+	maxPerIP      int     // Concurrent extractions allowed per client IP; 0 means unlimited.
+	minFreeDisk   uint64  // Bytes that must stay free in the temp directory; 0 disables the check.
+	rateLimit     float64 // Sustained requests per second allowed per client IP on /extract/.
+	rateBurst     int
+	trustProxy    bool // Take the client IP from X-Forwarded-For.
+
+	// rejectEmpty answers results that extracted nothing (see
+	// extractor.InvoiceDetails.Extracted) with 422 instead of a flagged 200.
+	rejectEmpty bool
+
+	// debugText allows requests to ask for the raw text of the document with
+	// the debug parameter. It exposes the whole PDF content, so it is off by
+	// default.
+	debugText bool
+
+	// jobTTL is how long finished asynchronous jobs are kept for polling, and
+	// jobTimeout how long the extraction of one may take. No client is waiting
+	// on a job, so it may run past the extraction timeout of a request.
+	jobTTL     time.Duration
+	jobTimeout time.Duration
+	// callbackAttempts caps the deliveries of a job to its callback URL, and
+	// callbackAllowHTTP accepts plain http callback URLs besides https.
+	callbackAttempts  int
+	callbackAllowHTTP bool
+	// callbackAllowPrivate lets callbacks reach loopback, private and
+	// link-local addresses, and callbackSecret, when set, keys the signature
+	// of every callback.
+	callbackAllowPrivate bool
+	callbackSecret       string
+
+	// templates are the named text/templates results can be rendered through,
+	// loaded from the templates directory.
+	templates map[string]*template.Template
+
+	// uploadFields lists the multipart field names the PDF is accepted under,
+	// in order of preference.
+	uploadFields []string
+
+	// logLevel is the minimum level logged; at debug the extractor logs each
+	// extracted result.
+	logLevel slog.Level
+	// logRedactions are masked out of every log record; empty disables redaction.
+	logRedactions []*regexp.Regexp
+
+	// apiKeyHashes holds the SHA-256 digests of the accepted API keys.
+	// An empty set disables authentication entirely.
+	apiKeyHashes [][32]byte
+
+	extractor extractor.Config
+}
+
+// loadConfig resolves the server configuration from the environment.
+// Invalid values are reported as errors rather than silently ignored.
+func loadConfig() (config, error) {
+	cfg := config{
+		addr:              ":8000",
+		readHeaderTimeout: 5 * time.Second,
+		readTimeout:       10 * time.Second,
+		writeTimeout:      30 * time.Second,
+		idleTimeout:       time.Minute,
+		maxConnections:    1000,
+		maxConcurrent:     maxConcurrentExtractions,
+		maxQueue:          100,
+		rateLimit:         100,
+		rateBurst:         20,
+		uploadFields:      []string{"file"},
+		jobTTL:            time.Hour,
+		jobTimeout:        5 * time.Minute,
+		callbackAttempts:  5,
+		extractor:         extractor.DefaultConfig(),
+	}
+	// Give up on an extraction in time to still answer within the write timeout.
+	cfg.extractor.Timeout = 25 * time.Second
+
+	if addr := strings.TrimSpace(os.Getenv(envPrefix + "ADDR")); addr != "" {
+		cfg.addr = addr
+	}
+	if raw := strings.TrimSpace(os.Getenv(envPrefix + "LOG_LEVEL")); raw != "" {
+		if err := cfg.logLevel.UnmarshalText([]byte(raw)); err != nil {
+			return cfg, fmt.Errorf("%sLOG_LEVEL: %q is not one of debug, info, warn, error", envPrefix, raw)
+		}
+	}
+	names, ok := os.LookupEnv(envPrefix + "LOG_REDACT")
+	if !ok {
+		names = defaultRedactions
+	}
+	redactions, err := parseRedactions(names, os.Getenv(envPrefix+"LOG_REDACT_PATTERNS"))
+	if err != nil {
+		return cfg, fmt.Errorf("%sLOG_REDACT: %w", envPrefix, err)
+	}
+	cfg.logRedactions = redactions
+
+	hashes, err := parseKeyHashes(os.Getenv(envPrefix + "API_KEY_HASHES"))
+	if err != nil {
+		return cfg, fmt.Errorf("%sAPI_KEY_HASHES: %w", envPrefix, err)
+	}
+	cfg.apiKeyHashes = hashes
+	if path := strings.TrimSpace(os.Getenv(envPrefix + "API_KEY_HASHES_FILE")); path != "" {
+		hashes, err := loadKeyHashes(path)
+		if err != nil {
+			return cfg, fmt.Errorf("%sAPI_KEY_HASHES_FILE: %w", envPrefix, err)
+		}
+		cfg.apiKeyHashes = append(cfg.apiKeyHashes, hashes...)
+	}
+
+	if cfg.readHeaderTimeout, err = envDuration("READ_HEADER_TIMEOUT", cfg.readHeaderTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.readHeaderTimeout <= 0 {
+		return cfg, fmt.Errorf("%sREAD_HEADER_TIMEOUT must be positive", envPrefix)
+	}
+	if cfg.maxConnections, err = envInt("MAX_CONNECTIONS", cfg.maxConnections); err != nil {
+		return cfg, err
+	}
+	if cfg.maxConnections < 0 {
+		return cfg, fmt.Errorf("%sMAX_CONNECTIONS must not be negative", envPrefix)
+	}
+
+	if cfg.maxConcurrent, err = envInt("MAX_CONCURRENT", cfg.maxConcurrent); err != nil {
+		return cfg, err
+	}
+	if cfg.maxConcurrent < 1 {
+		return cfg, fmt.Errorf("%sMAX_CONCURRENT must be positive", envPrefix)
+	}
+	if cfg.maxQueue, err = envInt("MAX_QUEUE", cfg.maxQueue); err != nil {
+		return cfg, err
+	}
+	if cfg.maxQueue < 0 {
+		return cfg, fmt.Errorf("%sMAX_QUEUE must not be negative", envPrefix)
+	}
+
+	if cfg.maxPerIP, err = envInt("MAX_CONCURRENT_PER_IP", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.maxPerIP < 0 {
+		return cfg, fmt.Errorf("%sMAX_CONCURRENT_PER_IP must not be negative", envPrefix)
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(envPrefix + "RATE_LIMIT")); raw != "" {
+		if cfg.rateLimit, err = strconv.ParseFloat(raw, 64); err != nil {
+			return cfg, fmt.Errorf("%sRATE_LIMIT: %q is not a number", envPrefix, raw)
+		}
+	}
+	if cfg.rateLimit <= 0 {
+		return cfg, fmt.Errorf("%sRATE_LIMIT must be positive", envPrefix)
+	}
+	if cfg.rateBurst, err = envInt("RATE_BURST", cfg.rateBurst); err != nil {
+		return cfg, err
+	}
+	if cfg.rateBurst < 1 {
+		return cfg, fmt.Errorf("%sRATE_BURST must be positive", envPrefix)
+	}
+	if cfg.trustProxy, err = envBool("TRUST_PROXY", false); err != nil {
+		return cfg, err
+	}
+
+	minFreeMB, err := envInt("MIN_FREE_DISK_MB", 100)
+	if err != nil {
+		return cfg, err
+	}
+	if minFreeMB < 0 {
+		return cfg, fmt.Errorf("%sMIN_FREE_DISK_MB must not be negative", envPrefix)
+	}
+	cfg.minFreeDisk = uint64(minFreeMB) << 20
+
+	if fields := splitList(os.Getenv(envPrefix + "UPLOAD_FIELDS")); len(fields) > 0 {
+		cfg.uploadFields = fields
+	}
+
+	if code, ok := os.LookupEnv(envPrefix + "DEFAULT_COUNTRY_CODE"); ok {
+		cfg.extractor.DefaultCountryCode = strings.TrimPrefix(strings.TrimSpace(code), "+")
+	}
+	if python := strings.TrimSpace(os.Getenv(envPrefix + "PYTHON")); python != "" {
+		cfg.extractor.PythonPath = python
+	}
+	if script := strings.TrimSpace(os.Getenv(envPrefix + "SCRIPT")); script != "" {
+		cfg.extractor.ScriptPath = script
+	}
+	if err := extractor.ResolvePythonPaths(&cfg.extractor); err != nil {
+		return cfg, fmt.Errorf("python backend: %w", err)
+	}
+	if gstins := splitList(os.Getenv(envPrefix + "SELLER_GSTIN")); len(gstins) > 0 {
+		cfg.extractor.SellerGSTINs = gstins
+	}
+	if labels := splitList(os.Getenv(envPrefix + "TOTAL_LABELS")); len(labels) > 0 {
+		cfg.extractor.TotalLabels = labels
+	}
+	if shape, ok := os.LookupEnv(envPrefix + "INVOICE_NUMBER_SHAPE"); ok {
+		cfg.extractor.InvoiceNumberShape = strings.TrimSpace(shape)
+	}
+	if labels, ok := os.LookupEnv(envPrefix + "BILLING_LABELS"); ok {
+		cfg.extractor.BillingLabels = splitList(labels)
+	}
+	if labels, ok := os.LookupEnv(envPrefix + "PRODUCT_CODE_LABELS"); ok {
+		cfg.extractor.ProductCodeLabels = splitList(labels)
+	}
+	if pattern := strings.TrimSpace(os.Getenv(envPrefix + "PRODUCT_CODE_PATTERN")); pattern != "" {
+		cfg.extractor.ProductCodePattern = pattern
+	}
+	if terms, ok := os.LookupEnv(envPrefix + "ADDRESS_TERMINATORS"); ok {
+		cfg.extractor.AddressTerminators = splitList(terms)
+	}
+	if pattern, ok := os.LookupEnv(envPrefix + "POSTAL_CODE_PATTERN"); ok {
+		cfg.extractor.PostalCodePattern = strings.TrimSpace(pattern)
+	}
+	if labels := splitList(os.Getenv(envPrefix + "GST_LABELS")); len(labels) > 0 {
+		cfg.extractor.GSTLabels = labels
+	}
+	cfg.extractor.DateLayout = os.Getenv(envPrefix + "DATE_LAYOUT")
+	if order := strings.ToLower(strings.TrimSpace(os.Getenv(envPrefix + "DATE_ORDER"))); order != "" {
+		cfg.extractor.DateOrder = order
+	}
+	if cfg.extractor.AmountPrecision, err = envInt("AMOUNT_PRECISION", cfg.extractor.AmountPrecision); err != nil {
+		return cfg, err
+	}
+	if path := strings.TrimSpace(os.Getenv(envPrefix + "RULES_FILE")); path != "" {
+		rules, err := extractor.LoadRules(path)
+		if err != nil {
+			return cfg, fmt.Errorf("%sRULES_FILE: %w", envPrefix, err)
+		}
+		cfg.extractor.FieldRules, cfg.extractor.CustomFields = rules.Fields, rules.CustomFields
+	}
+	if cfg.extractor.IDRules, err = loadIDRules(); err != nil {
+		return cfg, err
+	}
+	startMonth, err := envInt("FISCAL_YEAR_START_MONTH", int(cfg.extractor.FiscalYearStartMonth))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.extractor.FiscalYearStartMonth = time.Month(startMonth)
+	maxTextKB, err := envInt("MAX_TEXT_KB", cfg.extractor.MaxTextSize>>10)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.extractor.MaxTextSize = maxTextKB << 10
+	if engines := splitList(os.Getenv(envPrefix + "ENGINES")); len(engines) > 0 {
+		cfg.extractor.Engines = engines
+	}
+	if dir := strings.TrimSpace(os.Getenv(envPrefix + "TEMPLATES_DIR")); dir != "" {
+		if cfg.templates, err = loadTemplates(dir); err != nil {
+			return cfg, fmt.Errorf("%sTEMPLATES_DIR: %w", envPrefix, err)
+		}
+	}
+	if cfg.extractor.MinPopulatedFields, err = envInt("MIN_FIELDS", cfg.extractor.MinPopulatedFields); err != nil {
+		return cfg, err
+	}
+	switch policy := strings.ToLower(strings.TrimSpace(os.Getenv(envPrefix + "EMPTY_RESULT"))); policy {
+	case "", "flag":
+	case "reject":
+		cfg.rejectEmpty = true
+	default:
+		return cfg, fmt.Errorf("%sEMPTY_RESULT: %q is not one of flag, reject", envPrefix, policy)
+	}
+	if cfg.debugText, err = envBool("DEBUG_TEXT", false); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.MaxNotesSize, err = envInt("MAX_NOTES_CHARS", cfg.extractor.MaxNotesSize); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.MaxPages, err = envInt("MAX_PAGES", cfg.extractor.MaxPages); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.AddressLines, err = envBool("ADDRESS_LINES", false); err != nil {
+		return cfg, err
+	}
+	cfg.extractor.DigitGrouping = strings.ToLower(strings.TrimSpace(os.Getenv(envPrefix + "DIGIT_GROUPING")))
+	if cfg.extractor.PythonWorkers, err = envInt("PYTHON_WORKERS", cfg.maxConcurrent); err != nil {
+		return cfg, err
+	}
+	if cfg.jobTTL, err = envDuration("JOB_TTL", cfg.jobTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.jobTTL <= 0 {
+		return cfg, fmt.Errorf("%sJOB_TTL must be positive", envPrefix)
+	}
+	if cfg.jobTimeout, err = envDuration("JOB_TIMEOUT", cfg.jobTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.jobTimeout <= 0 {
+		return cfg, fmt.Errorf("%sJOB_TIMEOUT must be positive", envPrefix)
+	}
+	if cfg.callbackAttempts, err = envInt("CALLBACK_ATTEMPTS", cfg.callbackAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.callbackAttempts < 1 || cfg.callbackAttempts > maxCallbackAttempts {
+		return cfg, fmt.Errorf("%sCALLBACK_ATTEMPTS must be between 1 and %d", envPrefix, maxCallbackAttempts)
+	}
+	if cfg.callbackAllowHTTP, err = envBool("CALLBACK_ALLOW_HTTP", false); err != nil {
+		return cfg, err
+	}
+	if cfg.callbackAllowPrivate, err = envBool("CALLBACK_ALLOW_PRIVATE", false); err != nil {
+		return cfg, err
+	}
+	cfg.callbackSecret = os.Getenv(envPrefix + "CALLBACK_SECRET")
+	if cfg.extractor.Timeout, err = envDuration("EXTRACTION_TIMEOUT", cfg.extractor.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.CacheSize, err = envInt("CACHE_SIZE", cfg.extractor.CacheSize); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.OCRFallbackChars, err = envInt("OCR_FALLBACK_CHARS", cfg.extractor.OCRFallbackChars); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.CombinedText, err = envBool("COMBINED_TEXT", false); err != nil {
+		return cfg, err
+	}
+	if cfg.extractor.Validate, err = envBool("VALIDATE", false); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// LogValue implements slog.LogValuer so the configuration can be logged as one
+// structured record. Secrets are reduced to counts and never written out.
+func (cfg config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("addr", cfg.addr),
+		slog.Duration("read_header_timeout", cfg.readHeaderTimeout),
+		slog.Duration("read_timeout", cfg.readTimeout),
+		slog.Duration("write_timeout", cfg.writeTimeout),
+		slog.Duration("idle_timeout", cfg.idleTimeout),
+		slog.Int("max_connections", cfg.maxConnections),
+		slog.Int("max_concurrent_extractions", cfg.maxConcurrent),
+		slog.Int("python_workers", cfg.extractor.PythonWorkers),
+		slog.Int("max_queue", cfg.maxQueue),
+		slog.Int("max_concurrent_per_ip", cfg.maxPerIP),
+		slog.Uint64("min_free_disk_bytes", cfg.minFreeDisk),
+		slog.Float64("rate_limit_rps", cfg.rateLimit),
+		slog.Int("rate_limit_burst", cfg.rateBurst),
+		slog.Bool("trust_proxy", cfg.trustProxy),
+		slog.Any("upload_fields", cfg.uploadFields),
+		slog.Bool("reject_empty_results", cfg.rejectEmpty),
+		slog.Bool("debug_text", cfg.debugText),
+		slog.Int("templates", len(cfg.templates)),
+		slog.Duration("job_ttl", cfg.jobTTL),
+		slog.Duration("job_timeout", cfg.jobTimeout),
+		slog.Int("callback_attempts", cfg.callbackAttempts),
+		slog.Bool("callback_allow_http", cfg.callbackAllowHTTP),
+		slog.Bool("callback_allow_private", cfg.callbackAllowPrivate),
+		slog.Bool("callback_signed", cfg.callbackSecret != ""),
+		slog.String("log_level", cfg.logLevel.String()),
+		slog.Int("log_redactions", len(cfg.logRedactions)),
+		slog.Bool("auth_enabled", len(cfg.apiKeyHashes) > 0),
+		slog.Int("api_keys", len(cfg.apiKeyHashes)),
+		slog.String("python", cfg.extractor.PythonPath),
+		slog.String("script", cfg.extractor.ScriptPath),
+		slog.Any("extractor", cfg.extractor),
+	)
+}
+
+// loadIDRules reads the normalization rule of each ID field from
+// SIMPLEINVOICE_<FIELD>_STRIP_PREFIXES (comma-separated) and
+// SIMPLEINVOICE_<FIELD>_STRIP_ZEROS (boolean), e.g. SIMPLEINVOICE_INVOICE_NUMBER_STRIP_ZEROS.
+func loadIDRules() (map[string]extractor.IDRule, error) {
+	rules := make(map[string]extractor.IDRule)
+	for _, field := range extractor.IDFields {
+		name := strings.ToUpper(field)
+		rule := extractor.IDRule{StripPrefixes: splitList(os.Getenv(envPrefix + name + "_STRIP_PREFIXES"))}
+
+		var err error
+		if rule.StripLeadingZeros, err = envBool(name+"_STRIP_ZEROS", false); err != nil {
+			return nil, err
+		}
+		if len(rule.StripPrefixes) > 0 || rule.StripLeadingZeros {
+			rules[field] = rule
+		}
+	}
+	return rules, nil
+}
+
+// envBool reads the boolean environment variable envPrefix+name,
+// returning def when it is unset.
+func envBool(name string, def bool) (bool, error) {
+	raw, ok := os.LookupEnv(envPrefix + name)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return false, fmt.Errorf("%s%s: %q is not a boolean", envPrefix, name, raw)
+	}
+	return v, nil
+}
+
+// envInt reads the integer environment variable envPrefix+name,
+// returning def when it is unset.
+func envInt(name string, def int) (int, error) {
+	raw, ok := os.LookupEnv(envPrefix + name)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%s%s: %q is not an integer", envPrefix, name, raw)
+	}
+	return n, nil
+}
+
+// envDuration reads the duration environment variable envPrefix+name, written
+// like "5s" or "500ms", returning def when it is unset.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	raw, ok := os.LookupEnv(envPrefix + name)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%s%s: %q is not a duration", envPrefix, name, raw)
+	}
+	return d, nil
+}
+
+// loadKeyHashes reads API key digests from a file, one hex-encoded SHA-256
+// digest per line. Blank lines and lines starting with "#" are skipped, so
+// keys can be annotated with their owner.
+func loadKeyHashes(path string) ([][32]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	hashes, err := parseKeyHashes(strings.Join(lines, ","))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return hashes, nil
+}
+
+// parseKeyHashes parses a comma-separated list of hex-encoded SHA-256 digests.
+// Keys are only ever stored hashed, so a leaked config does not leak credentials.
+func parseKeyHashes(raw string) ([][32]byte, error) {
+	var hashes [][32]byte
+	for _, field := range splitList(raw) {
+		decoded, err := hex.DecodeString(field)
+		if err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("%q is not a hex-encoded SHA-256 digest", field)
+		}
+		hashes = append(hashes, [32]byte(decoded))
+	}
+	return hashes, nil
+}
+
+// splitList splits a comma-separated value, dropping blank entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, field := range strings.Split(raw, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			out = append(out, field)
+		}
+	}
+	return out
+}
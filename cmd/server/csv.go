@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// wantsCSV reports whether the Accept header asks for CSV ahead of JSON. The
+// first of text/csv, application/json or a wildcard listed wins; quality
+// values are not weighed.
+func wantsCSV(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return true
+		case "application/json", "application/*", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+// writeCSV responds with details as a CSV header line and one record.
+func (app *api) writeCSV(w http.ResponseWriter, r *http.Request, details *extractor.InvoiceDetails, filename string) {
+	var buf bytes.Buffer
+	if err := details.WriteCSV(&buf); err != nil {
+		app.log(r.Context()).Error("failed to write csv", "error", err, "filename", filename)
+		app.errorResponse(w, r, http.StatusInternalServerError, "server error")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		app.log(r.Context()).Error("failed to write csv response", "error", err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// hasDiskSpace is a pre-flight check verifying the temp directory can hold another
+// extraction: the configured reserve plus the declared size of the upload.
+// When it can't, it writes a 503 and reports false, turning a disk-full crash
+// into backpressure. Failure to read the free space is logged and tolerated.
+func (app *api) hasDiskSpace(w http.ResponseWriter, r *http.Request) bool {
+	if app.config.minFreeDisk == 0 {
+		return true
+	}
+
+	free, err := freeDiskSpace(os.TempDir())
+	if err != nil {
+		app.log(r.Context()).Warn("could not determine free disk space", "dir", os.TempDir(), "error", err)
+		return true
+	}
+
+	needed := app.config.minFreeDisk
+	if r.ContentLength > 0 {
+		needed += uint64(r.ContentLength)
+	}
+	if free < needed {
+		app.log(r.Context()).Error("insufficient disk space for upload", "free_bytes", free, "needed_bytes", needed)
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "server is low on disk space, try again later")
+		return false
+	}
+	if free < 2*app.config.minFreeDisk {
+		app.log(r.Context()).Warn("disk space is running low", "dir", os.TempDir(), "free_bytes", free)
+	}
+	return true
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "errors"
+
+// freeDiskSpace is not supported on this platform.
+func freeDiskSpace(dir string) (uint64, error) {
+	return 0, errors.New("free disk space is not available on this platform")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// fieldsHandler describes the extractable fields, their patterns and the text
+// layout each is read from, for building configuration tooling.
+func (app *api) fieldsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"fields": extractor.Fields()}, nil); err != nil {
+		app.log(r.Context()).Error("failed to write fields response", "error", err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// Job states reported by GET /extract/jobs/{id}.
+const (
+	jobPending = "pending"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// job is one asynchronous extraction. Its fields are guarded by jobStore.mu.
+type job struct {
+	ID       string                    `json:"id"`
+	Status   string                    `json:"status"`
+	Filename string                    `json:"filename"`
+	Details  *extractor.InvoiceDetails `json:"details,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+	Problems []string                  `json:"problems,omitempty"`
+
+	// finished is when the job left the pending state; it expires config.jobTTL later.
+	finished time.Time
+	// callbackURL, when set, is posted the job once it finishes.
+	callbackURL string
+}
+
+// jobStore holds the asynchronous jobs in memory. Finished jobs are dropped
+// once their TTL has passed; pending jobs are kept until they finish.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	ttl  time.Duration
+}
+
+func newJobStore(ttl time.Duration) *jobStore {
+	return &jobStore{jobs: make(map[string]*job), ttl: ttl}
+}
+
+// add registers a new pending job for filename and returns it.
+func (s *jobStore) add(filename, callbackURL string) *job {
+	var id [16]byte
+	rand.Read(id[:])
+	j := &job{ID: hex.EncodeToString(id[:]), Status: jobPending, Filename: filename, callbackURL: callbackURL}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+	s.jobs[j.ID] = j
+	return j
+}
+
+// get returns a snapshot of the job with the given id.
+func (s *jobStore) get(id string) (job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+	j, ok := s.jobs[id]
+	if !ok {
+		return job{}, false
+	}
+	return *j, true
+}
+
+// finish records the outcome of j, moving it out of the pending state, and
+// returns a snapshot of the finished job.
+func (s *jobStore) finish(j *job, update func(*job)) job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(j)
+	j.finished = time.Now()
+	return *j
+}
+
+// expire drops the finished jobs whose TTL has passed. s.mu must be held.
+func (s *jobStore) expire(now time.Time) {
+	for id, j := range s.jobs {
+		if j.Status != jobPending && now.Sub(j.finished) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// asyncExtractHandler accepts an upload like extractHandler, but answers 202
+// with a job ID at once and extracts in the background. The result is fetched
+// from jobHandler, or posted to the callback_url given with the upload.
+// Extraction still waits for a slot of the semaphore.
+func (app *api) asyncExtractHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	opts, err := app.extractOptions(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !app.hasDiskSpace(w, r) {
+		return
+	}
+
+	pdfs, filename, ok := app.readParts(w, r)
+	if !ok || !app.requirePDF(w, r, filename, pdfs...) {
+		return
+	}
+
+	var callbackURL string
+	if raw := r.FormValue("callback_url"); raw != "" {
+		if callbackURL, err = app.parseCallbackURL(raw); err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	j := app.jobs.add(filename, callbackURL)
+	app.log(r.Context()).Info("queued extraction job", "job", j.ID, "filename", filename)
+	go app.runJob(context.WithoutCancel(r.Context()), j, pdfs, opts)
+
+	statusURL := "/extract/jobs/" + j.ID
+	headers := http.Header{"Location": []string{statusURL}}
+	payload := map[string]any{"id": j.ID, "status": jobPending, "status_url": statusURL}
+	if err := app.writeJSON(w, http.StatusAccepted, payload, headers); err != nil {
+		app.log(r.Context()).Error("failed to write job response", "error", err)
+	}
+}
+
+// runJob extracts the parts of an asynchronous job and records the outcome.
+// It is not tied to the request, which has already been answered, so it is
+// bounded by the job timeout rather than the extraction timeout; ctx only
+// carries the request's logger.
+func (app *api) runJob(ctx context.Context, j *job, pdfs [][]byte, opts extractor.Options) {
+	defer func() {
+		if v := recover(); v != nil {
+			app.log(ctx).Error("extraction job panicked", "job", j.ID, "panic", v)
+			app.jobFinished(ctx, app.jobs.finish(j, func(j *job) {
+				j.Status, j.Error = jobFailed, "failed to extract details from PDF"
+			}))
+		}
+	}()
+
+	if err := app.acquireSlot(ctx); err != nil {
+		app.jobFinished(ctx, app.jobs.finish(j, func(j *job) {
+			j.Status, j.Error = jobFailed, "not extracted: "+err.Error()
+		}))
+		return
+	}
+	defer app.releaseSlot()
+
+	opts.Timeout = app.config.jobTimeout
+	parts := make([]io.Reader, len(pdfs))
+	for i, pdf := range pdfs {
+		parts[i] = bytes.NewReader(pdf)
+	}
+	details, err := timedExtraction(app.metrics, func() (*extractor.InvoiceDetails, error) {
+		return extractor.ExtractDetailsFromPartsContext(ctx, parts, opts)
+	})
+
+	var ambiguity *extractor.AmbiguityError
+	app.jobFinished(ctx, app.jobs.finish(j, func(j *job) {
+		switch {
+		case errors.As(err, &ambiguity):
+			j.Status, j.Error, j.Problems = jobFailed, "ambiguous extraction rejected in strict mode", ambiguity.Problems
+		case err != nil:
+			j.Status = jobFailed
+			_, j.Error = app.extractionError(ctx, err, j.Filename)
+		case !details.Extracted && app.config.rejectEmpty:
+			j.Status, j.Error = jobFailed, errNoFieldsExtracted
+		default:
+			j.Status, j.Details = jobDone, details
+		}
+	}))
+}
+
+// jobFinished logs the outcome of a finished job and delivers it to the job's
+// callback URL, if it has one. Delivery runs on its own so that its retries do
+// not hold the extraction slot.
+func (app *api) jobFinished(ctx context.Context, j job) {
+	app.log(ctx).Info("extraction job finished", "job", j.ID, "status", j.Status)
+	if j.callbackURL != "" {
+		go app.deliverCallback(ctx, j)
+	}
+}
+
+// jobHandler reports the state of an asynchronous job, with its result once done.
+func (app *api) jobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	j, ok := app.jobs.get(r.PathValue("id"))
+	if !ok {
+		app.errorResponse(w, r, http.StatusNotFound, "job not found or expired")
+		return
+	}
+	if err := app.writeJSON(w, http.StatusOK, j, nil); err != nil {
+		app.log(r.Context()).Error("failed to write job response", "error", err)
+	}
+}
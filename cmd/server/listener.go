@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"sync"
+)
+
+// limitListener returns a listener that holds at most n connections open at once.
+// Further connections wait in the kernel backlog until one closes, so slow
+// clients cannot exhaust the process's file descriptors.
+func limitListener(l net.Listener, n int) net.Listener {
+	return &limitedListener{Listener: l, slots: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+type limitedListener struct {
+	net.Listener
+	slots     chan struct{}
+	done      chan struct{} // Closed by Close to unblock a waiting Accept.
+	closeOnce sync.Once
+}
+
+// Accept waits for a free slot before accepting the next connection.
+func (l *limitedListener) Accept() (net.Conn, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.slots
+		return nil, err
+	}
+	return &limitedConn{Conn: conn, release: func() { <-l.slots }}, nil
+}
+
+// limitedConn frees its listener slot when closed, however many times Close is called.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// Close closes the underlying listener and wakes an Accept waiting for a slot.
+func (l *limitedListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
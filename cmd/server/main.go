@@ -4,62 +4,93 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"os/exec"
 	"runtime"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
-
-	"golang.org/x/time/rate"
 )
 
 // api holds application-wide dependencies like the logger and configuration.
 type api struct {
 	logger    *slog.Logger
-	limiter   *rate.Limiter
+	config    config
+	limiter   *limiterByIP  // Per-client request rate, see rateLimit.
 	semaphore chan struct{} // Used to limit concurrent extractions.
+	warm      atomic.Bool   // Set once the Python backend has completed a warmup.
+	inflight  *inflightByIP // Per-client in-flight counts; nil when unlimited.
+	queued    atomic.Int64  // Requests waiting for a semaphore slot.
+	metrics   *metrics
+	jobs      *jobStore    // Asynchronous extractions, see asyncExtractHandler.
+	callbacks *http.Client // Posts job results to callback URLs, see newCallbackClient.
+	readiness readiness    // Cached outcome of the /ready backend check.
 }
 
-// maxConcurrentExtractions defines how many PDF extractions can run at the same time.
-// This prevents the server from being overwhelmed by spawning too many Python processes.
+// maxConcurrentExtractions is the default of how many PDF extractions can run at the
+// same time, see SIMPLEINVOICE_MAX_CONCURRENT. The limit prevents the server from being
+// overwhelmed by spawning too many Python processes.
 const maxConcurrentExtractions = 10
 
 // NewAPI initializes and returns a new api struct with all dependencies.
-func NewAPI(logger *slog.Logger) *api {
-	return &api{
+func NewAPI(logger *slog.Logger, cfg config) *api {
+	app := &api{
 		logger:    logger,
-		limiter:   rate.NewLimiter(rate.Limit(100), 20), // Allow 2 req/sec with a burst of 5.
-		semaphore: make(chan struct{}, maxConcurrentExtractions),
+		config:    cfg,
+		limiter:   newLimiterByIP(cfg.rateLimit, cfg.rateBurst),
+		semaphore: make(chan struct{}, cfg.maxConcurrent),
+		metrics:   newMetrics(),
+		jobs:      newJobStore(cfg.jobTTL),
+		callbacks: newCallbackClient(cfg.callbackAllowPrivate),
+	}
+	if cfg.maxPerIP > 0 {
+		app.inflight = newInflightByIP(cfg.maxPerIP)
 	}
+	return app
 }
 
 // routes sets up the application's router with all the necessary handlers and middleware.
 func (app *api) routes() http.Handler {
 	mux := http.NewServeMux()
 
-	// Serve index.html at root
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			// Try to serve static files from ./web
-			http.ServeFile(w, r, "./web"+r.URL.Path)
-			return
-		}
-		http.ServeFile(w, r, "./web/index.html")
-	})
+	// Serve the web interface from ./web, with index.html at the root.
+	mux.Handle("/", http.FileServer(http.Dir("./web")))
 
 	// API endpoints
 	mux.HandleFunc("/health", app.healthCheckHandler)
-	mux.Handle("/extract/", app.rateLimit(http.HandlerFunc(app.extractHandler)))
+	mux.HandleFunc("/ready", app.readyHandler)
+	mux.HandleFunc("/metrics", app.metricsHandler)
+	mux.HandleFunc("/warmup", app.warmupHandler)
+	mux.Handle("POST /warmup", app.protect(app.runWarmupHandler))
+	mux.HandleFunc("/config/fields", app.fieldsHandler)
+	mux.Handle("/extract/", app.protect(app.extractHandler))
+	mux.Handle("/extract/annotate", app.protect(app.annotateHandler))
+	mux.Handle("/extract/batch", app.protect(app.batchHandler))
+	mux.Handle("/extract/batch/stream", app.protect(app.batchStreamHandler))
+	mux.Handle("/extract/totals", app.protect(app.totalsHandler))
+	mux.Handle("/extract/async", app.protect(app.asyncExtractHandler))
+	mux.Handle("/extract/jobs/{id}", app.protect(app.jobHandler))
+
+	return app.withRequestID(mux)
+}
 
-	return mux
+// protect wraps an extraction endpoint in the rate limiting, authentication
+// and per-client concurrency middleware.
+func (app *api) protect(h http.HandlerFunc) http.Handler {
+	return app.rateLimit(app.requireAPIKey(app.limitPerIP(h)))
 }
 
 // writeJSON is a helper for sending structured JSON responses to the client.
@@ -84,15 +115,75 @@ func (app *api) writeJSON(w http.ResponseWriter, status int, data any, headers h
 func (app *api) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	errPayload := map[string]any{"error": message}
 	if err := app.writeJSON(w, status, errPayload, nil); err != nil {
-		app.logger.Error("failed to write error json response", "error", err)
+		app.log(r.Context()).Error("failed to write error json response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-// rateLimit is a middleware that checks if a request is allowed by the rate limiter.
+// errNoFieldsExtracted is the error returned for results that extracted nothing
+// when empty results are rejected.
+const errNoFieldsExtracted = "no fields extracted"
+
+// requirePDF checks that every upload starts with the PDF header, answering
+// 400 and reporting false otherwise.
+func (app *api) requirePDF(w http.ResponseWriter, r *http.Request, filename string, pdfs ...[]byte) bool {
+	for _, pdf := range pdfs {
+		if !extractor.IsPDF(pdf) {
+			app.log(r.Context()).Info("rejected non-PDF upload", "filename", filename)
+			app.errorResponse(w, r, http.StatusBadRequest, "the uploaded file is not a PDF")
+			return false
+		}
+	}
+	return true
+}
+
+// extractionError maps a failed extraction to the status and message returned
+// to the client, and logs it to the logger of ctx at a level matching whose
+// fault it is.
+func (app *api) extractionError(ctx context.Context, err error, filename string) (int, string) {
+	switch {
+	case errors.Is(err, extractor.ErrExtractionTimeout):
+		app.log(ctx).Warn("extraction timed out", "error", err, "filename", filename)
+		return http.StatusGatewayTimeout, "extraction timed out"
+	case errors.Is(err, extractor.ErrTooManyPages):
+		app.log(ctx).Info("rejected long PDF", "error", err, "filename", filename)
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("the PDF has too many pages; at most %d are accepted", app.config.extractor.MaxPages)
+	case errors.Is(err, extractor.ErrNotPDF):
+		app.log(ctx).Info("rejected non-PDF upload", "filename", filename)
+		return http.StatusBadRequest, "the uploaded file is not a PDF"
+	case errors.Is(err, extractor.ErrInvalidPDF):
+		app.log(ctx).Info("unreadable PDF", "error", err, "filename", filename)
+		return http.StatusBadRequest, "the PDF could not be read; it may be corrupt, truncated or password-protected"
+	case errors.Is(err, extractor.ErrPythonUnavailable):
+		app.log(ctx).Error("text extraction unavailable", "error", err, "filename", filename)
+		return http.StatusServiceUnavailable, "text extraction is unavailable, please retry later"
+	}
+	app.log(ctx).Error("extraction failed", "error", err, "filename", filename)
+	return http.StatusInternalServerError, "failed to extract details from PDF"
+}
+
+// extractionFailed logs a failed extraction and writes the matching error response:
+// 422 listing the problems when strict mode refused an ambiguous result, otherwise
+// the status chosen by extractionError.
+func (app *api) extractionFailed(w http.ResponseWriter, r *http.Request, err error, filename string) {
+	var ambiguity *extractor.AmbiguityError
+	if errors.As(err, &ambiguity) {
+		app.log(r.Context()).Info("strict extraction rejected", "filename", filename, "problems", len(ambiguity.Problems))
+		payload := map[string]any{"error": "ambiguous extraction rejected in strict mode", "problems": ambiguity.Problems}
+		if err := app.writeJSON(w, http.StatusUnprocessableEntity, payload, nil); err != nil {
+			app.log(r.Context()).Error("failed to write error json response", "error", err)
+		}
+		return
+	}
+	status, message := app.extractionError(r.Context(), err, filename)
+	app.errorResponse(w, r, status, message)
+}
+
+// rateLimit is a middleware that checks if a request is allowed by the rate
+// limiter of the client IP that sent it.
 func (app *api) rateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !app.limiter.Allow() {
+		if !app.limiter.allow(app.clientIP(r)) {
 			app.errorResponse(w, r, http.StatusTooManyRequests, "rate limit exceeded")
 			return
 		}
@@ -102,13 +193,15 @@ func (app *api) rateLimit(next http.Handler) http.Handler {
 
 // healthCheckHandler provides a simple health check endpoint for monitoring.
 func (app *api) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	healthInfo := map[string]string{
-		"status":      "available",
-		"environment": "development",
-		"version":     "1.0.0",
+	healthInfo := map[string]any{
+		"status":             "available",
+		"environment":        "development",
+		"version":            "1.0.0",
+		"extractions_active": len(app.semaphore),
+		"extractions_queued": app.queued.Load(),
 	}
 	if err := app.writeJSON(w, http.StatusOK, healthInfo, nil); err != nil {
-		app.logger.Error("failed to write health check response", "error", err)
+		app.log(r.Context()).Error("failed to write health check response", "error", err)
 		app.errorResponse(w, r, http.StatusInternalServerError, "server error")
 	}
 }
@@ -116,40 +209,101 @@ func (app *api) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 // extractHandler handles the primary logic of file upload and data extraction.
 // It is wrapped with concurrency controls to ensure server stability.
 func (app *api) extractHandler(w http.ResponseWriter, r *http.Request) {
-	// Acquire a slot from the semaphore. This will block if all slots are in use,
-	// providing a natural backpressure mechanism.
-	app.semaphore <- struct{}{}
-	// Defer releasing the slot so it's always freed when the function returns.
-	defer func() { <-app.semaphore }()
+	opts, err := app.extractOptions(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	flat, err := queryBool(r.URL.Query(), "flat")
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "table" {
+		app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("invalid view: %q is not one of: table", view))
+		return
+	}
+	if flat && view != "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "flat and view cannot be combined")
+		return
+	}
+	tmpl, err := app.requestTemplate(r)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if tmpl != nil && (flat || view != "") {
+		app.errorResponse(w, r, http.StatusBadRequest, "a template cannot be combined with flat or view")
+		return
+	}
+	if !app.hasDiskSpace(w, r) {
+		return
+	}
 
-	// 1. Parse multipart form with a reasonable limit (e.g., 10MB).
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		app.errorResponse(w, r, http.StatusBadRequest, "could not parse multipart form: "+err.Error())
+	// 1-2. Parse the multipart form and read the uploaded file, or its parts.
+	pdfs, filename, ok := app.readParts(w, r)
+	if !ok {
+		return
+	}
+	// Reject anything that is not a PDF before it takes up an extraction slot.
+	if !app.requirePDF(w, r, filename, pdfs...) {
 		return
 	}
 
-	// 2. Retrieve the file from the form data.
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		app.errorResponse(w, r, http.StatusBadRequest, "error retrieving the file from form-data")
+	// Acquire a slot from the semaphore. This will wait in a bounded queue if all
+	// slots are in use, providing a natural backpressure mechanism.
+	if !app.acquireSlotOrFail(w, r) {
 		return
 	}
-	defer file.Close()
+	// Defer releasing the slot so it's always freed when the function returns.
+	defer app.releaseSlot()
 
-	app.logger.Info("processing file", "filename", handler.Filename, "size_bytes", handler.Size)
+	size := 0
+	parts := make([]io.Reader, len(pdfs))
+	for i, pdf := range pdfs {
+		size += len(pdf)
+		parts[i] = bytes.NewReader(pdf)
+	}
+	app.log(r.Context()).Info("processing file", "filename", filename, "size_bytes", size, "parts", len(pdfs))
 
 	// 3. Pass the file to the extractor logic.
-	details, err := extractor.ExtractDetails(file)
+	details, err := timedExtraction(app.metrics, func() (*extractor.InvoiceDetails, error) {
+		return extractor.ExtractDetailsFromPartsContext(r.Context(), parts, opts)
+	})
 	if err != nil {
-		app.logger.Error("extraction failed", "error", err, "filename", handler.Filename)
-		app.errorResponse(w, r, http.StatusInternalServerError, "failed to extract details from PDF")
+		app.extractionFailed(w, r, err, filename)
+		return
+	}
+	if !details.Extracted && app.config.rejectEmpty {
+		app.log(r.Context()).Info("no fields extracted", "filename", filename)
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, errNoFieldsExtracted)
 		return
 	}
 
 	// 4. Send the successful JSON response.
-	app.logger.Info("extraction successful", "filename", handler.Filename)
-	if err := app.writeJSON(w, http.StatusOK, details, nil); err != nil {
-		app.logger.Error("failed to write successful json response", "error", err)
+	app.log(r.Context()).Info("extraction successful", "filename", filename)
+	if tmpl != nil {
+		app.renderTemplate(w, r, tmpl, details)
+		return
+	}
+	if !flat && view == "" && wantsCSV(r) {
+		app.writeCSV(w, r, details, filename)
+		return
+	}
+	var body any = details
+	switch {
+	case flat:
+		if body, err = details.Flatten(); err != nil {
+			app.log(r.Context()).Error("failed to flatten details", "error", err, "filename", filename)
+			app.errorResponse(w, r, http.StatusInternalServerError, "server error")
+			return
+		}
+	case view == "table":
+		body = details.TableView()
+	}
+	if err := app.writeJSON(w, http.StatusOK, body, nil); err != nil {
+		app.log(r.Context()).Error("failed to write successful json response", "error", err)
 	}
 }
 
@@ -158,7 +312,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 		// Allow all origins (for development only)
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
@@ -171,6 +326,19 @@ func corsMiddleware(next http.Handler) http.Handler {
 }
 
 
+// browserURL is the URL of the web interface served on addr. A wildcard host,
+// as in ":8000", is reached through localhost.
+func browserURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
 func openBrowser(url string) error {
     var cmd string
     var args []string
@@ -190,23 +358,59 @@ func openBrowser(url string) error {
     return exec.Command(cmd, args...).Start()
 }
 func main() {
+	// "extract <file.pdf>" runs a single extraction without the server.
+	if len(os.Args) > 1 && os.Args[1] == "extract" {
+		os.Exit(runExtract(os.Args[2:]))
+	}
+
 	// Use Go's new structured logger for machine-readable logs, essential for production.
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
-	app := NewAPI(logger)
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	// The flag takes precedence over SIMPLEINVOICE_ADDR.
+	flag.StringVar(&cfg.addr, "addr", cfg.addr, "`address` to listen on, e.g. :8000, or :0 for any free port")
+	flag.Parse()
+
+	// Rebuild the logger with the configured level, masking PII before it is written.
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.logLevel})
+	logger = slog.New(newRedactingHandler(handler, cfg.logRedactions))
+	extractor.SetLogger(logger)
+	if err := extractor.Configure(cfg.extractor); err != nil {
+		logger.Error("invalid extractor configuration", "error", err)
+		os.Exit(1)
+	}
+
+	app := NewAPI(logger, cfg)
+
+	// Remove the temp files of extractions killed mid-run by an earlier crash.
+	// An hour leaves alone those of any other instance sharing the directory.
+	if n, err := extractor.SweepTempFiles(time.Hour); err != nil {
+		logger.Warn("failed to remove stale temp files", "removed", n, "error", err)
+	} else if n > 0 {
+		logger.Info("removed stale temp files", "removed", n)
+	}
 
 	// --- Production-Ready Server Configuration ---
 	srv := &http.Server{
-		Addr:         ":8000",
-		Handler: corsMiddleware(app.routes()), // CORS enabled
-		IdleTimeout:  time.Minute,      // Prevents slow-loris attacks.
-		ReadTimeout:  10 * time.Second, // Max time to read request headers/body.
-		WriteTimeout: 30 * time.Second, // Max time to write response.
+		Addr:              cfg.addr,
+		Handler:           corsMiddleware(app.routes()), // CORS enabled
+		IdleTimeout:       cfg.idleTimeout,              // Prevents slow-loris attacks.
+		ReadHeaderTimeout: cfg.readHeaderTimeout,        // Max time to read request headers.
+		ReadTimeout:       cfg.readTimeout,              // Max time to read request headers/body.
+		WriteTimeout:      cfg.writeTimeout,             // Max time to write response.
 	}
 
 	// --- Graceful Shutdown Logic ---
 	shutdownError := make(chan error)
 
+	// The startup warmup is cancelled on shutdown rather than waited for.
+	warmupCtx, stopWarmup := context.WithCancel(context.Background())
+	defer stopWarmup()
+
 	go func() {
 		// Listen for interrupt signals (like Ctrl+C).
 		quit := make(chan os.Signal, 1)
@@ -214,6 +418,7 @@ func main() {
 		s := <-quit
 
 		logger.Info("shutting down server", "signal", s.String())
+		stopWarmup()
 
 		// Give active requests a deadline to finish.
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
@@ -222,31 +427,57 @@ func main() {
 		// Attempt to gracefully shut down the server.
 		if err := srv.Shutdown(ctx); err != nil {
 			shutdownError <- err
+			return
 		}
 
-		logger.Info("completing background tasks")
-		shutdownError <- nil
+		// Handlers have returned, but asynchronous jobs may still be running
+		// Python processes; let them finish within the same deadline.
+		logger.Info("completing background tasks", "extractions_active", len(app.semaphore))
+		shutdownError <- app.drain(ctx)
 	}()
 
-	logger.Info("starting server", "addr", srv.Addr)
+	logger.Info("resolved configuration", "config", cfg)
+
+	// Bind before announcing the address, so that with port 0 the port the
+	// system assigned is the one logged.
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Error("server failed to start", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("starting server", "addr", ln.Addr().String())
+	if cfg.maxConnections > 0 {
+		ln = limitListener(ln, cfg.maxConnections)
+	}
+
+	// Warm the Python backend in the background so the first upload is fast.
+	go func() {
+		if err := app.acquireSlot(warmupCtx); err != nil {
+			logger.Error("startup warmup failed", "error", err)
+			return
+		}
+		defer app.releaseSlot()
+		if _, err := app.warmup(warmupCtx); err != nil {
+			logger.Error("startup warmup failed", "error", err)
+		}
+	}()
 
-	
-    // Open browser in a goroutine after a tiny delay (to ensure server is ready)
-    go func() {
-        time.Sleep(500 * time.Millisecond) // give server a moment to start
-        url := "http://localhost" + srv.Addr
-        if err := openBrowser(url); err != nil {
-            logger.Error("failed to open browser", "error", err)
-        } else {
-            logger.Info("opened browser", "url", url)
-        }
-    }()
+	url := browserURL(ln.Addr())
+	// Open the web interface once the server has had a moment to start serving.
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		if err := openBrowser(url); err != nil {
+			logger.Error("failed to open browser", "error", err)
+		} else {
+			logger.Info("opened browser", "url", url)
+		}
+	}()
 
-    // Also log the URL so user can click or copy-paste
-    logger.Info("web interface available at", "url", "http://localhost"+srv.Addr)
+	// Also log the URL so user can click or copy-paste
+	logger.Info("web interface available at", "url", url)
 
 	// Start the server. This is a blocking call.
-	err := srv.ListenAndServe()
+	err = srv.Serve(ln)
 	if !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("server failed to start", "error", err)
 		os.Exit(1)
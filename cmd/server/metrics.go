@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the extraction duration
+// histogram.
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60}
+
+// metrics collects the extraction metrics exposed on /metrics in the
+// Prometheus text format.
+type metrics struct {
+	mu            sync.Mutex
+	bucketCounts  []uint64 // Observations per bucket, not cumulative.
+	durationSum   float64
+	durationCount uint64
+	outcomes      map[outcome]uint64
+}
+
+// outcome labels the extractions counter.
+type outcome struct {
+	result string // "success" or "failure"
+	stage  string // The stage a failure happened in, "none" on success.
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		bucketCounts: make([]uint64, len(durationBuckets)),
+		outcomes:     make(map[outcome]uint64),
+	}
+}
+
+// observeExtraction records one extraction that took d and ended with err.
+func (m *metrics) observeExtraction(d time.Duration, err error) {
+	o := outcome{result: "success", stage: "none"}
+	if err != nil {
+		o = outcome{result: "failure", stage: extractor.FailureStage(err)}
+		if o.stage == "" {
+			o.stage = "unknown"
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := d.Seconds()
+	if i, _ := slices.BinarySearch(durationBuckets, seconds); i < len(durationBuckets) {
+		m.bucketCounts[i]++
+	}
+	m.durationSum += seconds
+	m.durationCount++
+	m.outcomes[o]++
+}
+
+// timedExtraction runs extract and records its duration and outcome in m.
+func timedExtraction[T any](m *metrics, extract func() (T, error)) (T, error) {
+	start := time.Now()
+	result, err := extract()
+	m.observeExtraction(time.Since(start), err)
+	return result, err
+}
+
+// metricsHandler serves the metrics in the Prometheus text exposition format.
+func (app *api) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	m := app.metrics
+	m.mu.Lock()
+	buf.WriteString("# HELP simpleinvoice_extraction_duration_seconds End-to-end duration of PDF extractions.\n")
+	buf.WriteString("# TYPE simpleinvoice_extraction_duration_seconds histogram\n")
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		cumulative += m.bucketCounts[i]
+		fmt.Fprintf(&buf, "simpleinvoice_extraction_duration_seconds_bucket{le=\"%g\"} %d\n", bound, cumulative)
+	}
+	fmt.Fprintf(&buf, "simpleinvoice_extraction_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durationCount)
+	fmt.Fprintf(&buf, "simpleinvoice_extraction_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(&buf, "simpleinvoice_extraction_duration_seconds_count %d\n", m.durationCount)
+
+	buf.WriteString("# HELP simpleinvoice_extractions_total PDF extractions by result and failure stage.\n")
+	buf.WriteString("# TYPE simpleinvoice_extractions_total counter\n")
+	outcomes := make([]outcome, 0, len(m.outcomes))
+	for o := range m.outcomes {
+		outcomes = append(outcomes, o)
+	}
+	slices.SortFunc(outcomes, func(a, b outcome) int {
+		if a.result != b.result {
+			return strings.Compare(a.result, b.result)
+		}
+		return strings.Compare(a.stage, b.stage)
+	})
+	for _, o := range outcomes {
+		fmt.Fprintf(&buf, "simpleinvoice_extractions_total{result=%q,stage=%q} %d\n", o.result, o.stage, m.outcomes[o])
+	}
+	m.mu.Unlock()
+
+	buf.WriteString("# HELP simpleinvoice_extractions_in_flight Extractions holding a concurrency slot.\n")
+	buf.WriteString("# TYPE simpleinvoice_extractions_in_flight gauge\n")
+	fmt.Fprintf(&buf, "simpleinvoice_extractions_in_flight %d\n", len(app.semaphore))
+	buf.WriteString("# HELP simpleinvoice_extractions_queued Requests waiting for a concurrency slot.\n")
+	buf.WriteString("# TYPE simpleinvoice_extractions_queued gauge\n")
+	fmt.Fprintf(&buf, "simpleinvoice_extractions_queued %d\n", app.queued.Load())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := buf.WriteTo(w); err != nil {
+		app.log(r.Context()).Error("failed to write metrics", "error", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// maxOCRPages caps how many pages a single request may send through OCR,
+// since each page is rendered and recognised individually and is expensive.
+const maxOCRPages = 5
+
+// extractOptions builds the per-request extraction options from the query string.
+// Errors describe the offending parameter and are safe to return to the client.
+func (app *api) extractOptions(r *http.Request) (extractor.Options, error) {
+	var opts extractor.Options
+	query := r.URL.Query()
+
+	if raw := query.Get("ocr_pages"); raw != "" {
+		pages, err := parsePageList(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid ocr_pages: %w", err)
+		}
+		opts.OCRPages = pages
+	}
+
+	var err error
+	if opts.MatchedBy, err = queryBool(query, "matched_by"); err != nil {
+		return opts, err
+	}
+	if opts.Strict, err = queryBool(query, "strict"); err != nil {
+		return opts, err
+	}
+	if opts.RawText, err = queryBool(query, "debug"); err != nil {
+		return opts, err
+	}
+	if opts.RawText && !app.config.debugText {
+		return opts, errors.New("invalid debug: debug output is disabled on this server")
+	}
+
+	switch source := query.Get("text_source"); source {
+	case "", extractor.TextSourceAuto, extractor.TextSourceText, extractor.TextSourceOCR:
+		opts.TextSource = source
+	default:
+		return opts, fmt.Errorf("invalid text_source: %q is not one of: %s, %s, %s", source, extractor.TextSourceAuto, extractor.TextSourceText, extractor.TextSourceOCR)
+	}
+
+	if raw := query.Get("custom_fields"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.CustomFields); err != nil {
+			return opts, fmt.Errorf("invalid custom_fields: must be a JSON object mapping names to patterns: %w", err)
+		}
+		if err := extractor.ValidateCustomFields(opts.CustomFields); err != nil {
+			return opts, fmt.Errorf("invalid custom_fields: %w", err)
+		}
+	}
+
+	if raw := query.Get("max_ms"); raw != "" {
+		ms, err := strconv.Atoi(raw)
+		if err != nil || ms <= 0 {
+			return opts, fmt.Errorf("invalid max_ms: %q is not a positive number of milliseconds", raw)
+		}
+		opts.SoftTimeout = time.Duration(ms) * time.Millisecond
+	}
+
+	return opts, nil
+}
+
+// parsePageList parses a comma-separated list of 1-based page numbers.
+func parsePageList(raw string) ([]int, error) {
+	fields := strings.Split(raw, ",")
+	if len(fields) > maxOCRPages {
+		return nil, fmt.Errorf("at most %d pages may be requested", maxOCRPages)
+	}
+
+	pages := make([]int, 0, len(fields))
+	for _, field := range fields {
+		page, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("%q is not a page number", field)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// queryBool reads an optional boolean query parameter, defaulting to false.
+func queryBool(query url.Values, name string) (bool, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q is not a boolean", name, raw)
+	}
+	return v, nil
+}
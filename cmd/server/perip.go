@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// inflightByIP counts the requests each client IP currently has in flight.
+// Entries are deleted as soon as their count drops to zero, so idle clients
+// never accumulate and no separate eviction sweep is needed.
+type inflightByIP struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[string]int
+}
+
+func newInflightByIP(limit int) *inflightByIP {
+	return &inflightByIP{limit: limit, counts: make(map[string]int)}
+}
+
+// acquire reserves a slot for ip, reporting false when ip is already at its limit.
+func (l *inflightByIP) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[ip] >= l.limit {
+		return false
+	}
+	l.counts[ip]++
+	return true
+}
+
+// release frees a slot previously reserved for ip.
+func (l *inflightByIP) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[ip]--; l.counts[ip] <= 0 {
+		delete(l.counts, ip)
+	}
+}
+
+// limitPerIP is a middleware that caps how many requests a single client IP may
+// have in flight, so one client cannot monopolize every extraction slot.
+// It is a no-op when no per-IP limit is configured.
+func (app *api) limitPerIP(next http.Handler) http.Handler {
+	if app.inflight == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := app.clientIP(r)
+		if !app.inflight.acquire(ip) {
+			app.errorResponse(w, r, http.StatusTooManyRequests, "too many concurrent extractions from this client")
+			return
+		}
+		defer app.inflight.release(ip)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the IP address of the client that sent r. Behind a trusted
+// proxy this is the last address in X-Forwarded-For, the one the proxy itself
+// appended; earlier entries are supplied by the client and may be forged.
+func (app *api) clientIP(r *http.Request) string {
+	if app.config.trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
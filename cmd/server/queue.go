@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// errQueueFull is returned by acquireSlot when the wait queue is at capacity.
+var errQueueFull = errors.New("extraction queue is full")
+
+// acquireSlot takes an extraction slot from the semaphore. When every slot is
+// busy the caller waits in the queue, unless config.maxQueue callers are already
+// waiting, in which case it fails at once with errQueueFull. Waiting ends early
+// with the context's error when ctx is cancelled. A nil error must be paired
+// with a call to releaseSlot.
+func (app *api) acquireSlot(ctx context.Context) error {
+	select {
+	case app.semaphore <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if depth := app.queued.Add(1); app.config.maxQueue > 0 && depth > int64(app.config.maxQueue) {
+		app.queued.Add(-1)
+		return errQueueFull
+	}
+	defer app.queued.Add(-1)
+
+	select {
+	case app.semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSlot returns a slot taken by acquireSlot.
+func (app *api) releaseSlot() {
+	<-app.semaphore
+}
+
+// drain waits for the extractions in flight, asynchronous jobs included, to
+// finish, by taking every slot of the semaphore in turn. The slots are kept, so
+// no extraction starts afterwards. It gives up when ctx is done, reporting how
+// many extractions were still running.
+func (app *api) drain(ctx context.Context) error {
+	for held := 0; held < cap(app.semaphore); held++ {
+		select {
+		case app.semaphore <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("%d extractions still running: %w", cap(app.semaphore)-held, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// acquireSlotOrFail is acquireSlot for handlers. On failure it writes the
+// error response itself and reports false: 503 with a Retry-After when the queue
+// is full, nothing when the client has gone away.
+func (app *api) acquireSlotOrFail(w http.ResponseWriter, r *http.Request) bool {
+	err := app.acquireSlot(r.Context())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errQueueFull):
+		app.log(r.Context()).Warn("extraction queue full, rejecting request", "queued", app.queued.Load())
+		w.Header().Set("Retry-After", "1")
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "server is busy, please retry shortly")
+	default:
+		app.log(r.Context()).Info("client went away while queued", "error", err)
+	}
+	return false
+}
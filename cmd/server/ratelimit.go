@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minIdleLimiter is how long a client's limiter is kept after its last request
+// at the least. It is kept longer when its bucket takes longer to refill, so
+// evicting it never hands a client a fresher budget than waiting would have.
+const minIdleLimiter = 3 * time.Minute
+
+// limiterByIP gives each client IP its own token bucket, so one noisy client
+// exhausts only its own budget. Idle entries are evicted by a sweep that runs
+// at most once per idle period, on the requests themselves.
+type limiterByIP struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	idle      time.Duration
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newLimiterByIP(rps float64, burst int) *limiterByIP {
+	idle := minIdleLimiter
+	if refill := time.Duration(float64(burst) / rps * float64(time.Second)); refill > idle {
+		idle = refill
+	}
+	return &limiterByIP{
+		limit:     rate.Limit(rps),
+		burst:     burst,
+		idle:      idle,
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether ip may make a request now, spending one of its tokens.
+func (l *limiterByIP) allow(ip string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > l.idle {
+		l.evict(now)
+	}
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
+
+// evict drops the clients idle for longer than l.idle. l.mu must be held.
+func (l *limiterByIP) evict(now time.Time) {
+	for ip, c := range l.clients {
+		if now.Sub(c.lastSeen) > l.idle {
+			delete(l.clients, ip)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// One client spending its budget must not block another.
+func TestLimiterByIPSeparatesClients(t *testing.T) {
+	l := newLimiterByIP(1, 2)
+	for i := range 2 {
+		if !l.allow("192.0.2.1") {
+			t.Fatalf("request %d of the first client was refused within its burst", i+1)
+		}
+	}
+	if l.allow("192.0.2.1") {
+		t.Error("first client was allowed past its burst")
+	}
+	if !l.allow("192.0.2.2") {
+		t.Error("second client was refused because the first spent its budget")
+	}
+}
+
+func TestLimiterByIPEvictsIdleClients(t *testing.T) {
+	l := newLimiterByIP(1, 2)
+	l.allow("192.0.2.1")
+	l.allow("192.0.2.2")
+
+	later := time.Now().Add(l.idle + time.Second)
+	l.clients["192.0.2.2"].lastSeen = later
+	l.evict(later)
+
+	if _, ok := l.clients["192.0.2.1"]; ok {
+		t.Error("idle client was not evicted")
+	}
+	if _, ok := l.clients["192.0.2.2"]; !ok {
+		t.Error("active client was evicted")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		forwarded  string
+		want       string
+	}{
+		{name: "remote address", want: "198.51.100.7"},
+		{name: "forwarded header ignored without proxy", forwarded: "203.0.113.9", want: "198.51.100.7"},
+		{name: "last forwarded hop behind proxy", trustProxy: true, forwarded: "10.0.0.1, 203.0.113.9", want: "203.0.113.9"},
+		{name: "invalid forwarded hop", trustProxy: true, forwarded: "not-an-ip", want: "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &api{config: config{trustProxy: tt.trustProxy}}
+			r := httptest.NewRequest("GET", "/extract/", nil)
+			r.RemoteAddr = "198.51.100.7:51234"
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := app.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+const (
+	// readyCheckTTL is how long a readiness result is reused, so frequent
+	// probes do not each start a Python extraction.
+	readyCheckTTL = 5 * time.Second
+	// readyCheckTimeout bounds a single readiness check.
+	readyCheckTimeout = 10 * time.Second
+)
+
+// readiness caches the outcome of the last backend check.
+type readiness struct {
+	mu       sync.Mutex // Held during a check, so concurrent probes share it.
+	checked  time.Time
+	err      error
+	duration time.Duration
+}
+
+// check returns the outcome of a backend check no older than readyCheckTTL.
+func (rd *readiness) check() (time.Duration, error) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if time.Since(rd.checked) < readyCheckTTL {
+		return rd.duration, rd.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	rd.err = extractor.CheckBackend(ctx)
+	rd.duration = time.Since(start)
+	rd.checked = time.Now()
+	return rd.duration, rd.err
+}
+
+// readyHandler is the readiness probe. Unlike /health, which only shows the
+// process is up, it extracts the bundled sample PDF and answers 503 when the
+// Python pipeline does not work, so traffic is routed elsewhere.
+func (app *api) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	elapsed, err := app.readiness.check()
+	if err != nil {
+		app.log(r.Context()).Error("readiness check failed", "error", err)
+		payload := map[string]any{"ready": false, "error": "extraction backend is not available"}
+		if err := app.writeJSON(w, http.StatusServiceUnavailable, payload, nil); err != nil {
+			app.log(r.Context()).Error("failed to write readiness response", "error", err)
+		}
+		return
+	}
+	payload := map[string]any{"ready": true, "duration_ms": elapsed.Milliseconds()}
+	if err := app.writeJSON(w, http.StatusOK, payload, nil); err != nil {
+		app.log(r.Context()).Error("failed to write readiness response", "error", err)
+	}
+}
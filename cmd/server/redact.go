@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redactionMask replaces every redacted match in log output.
+const redactionMask = "[REDACTED]"
+
+// builtinRedactions are the named PII patterns that can be masked in logs. They
+// omit word boundaries so identifiers embedded in filenames are masked too.
+var builtinRedactions = map[string]*regexp.Regexp{
+	"gstin": regexp.MustCompile(`\d{2}[A-Z]{5}\d{4}[A-Z][1-9A-Z]Z[0-9A-Z]`),
+	"pan":   regexp.MustCompile(`[A-Z]{5}\d{4}[A-Z]`),
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	"phone": regexp.MustCompile(`\+?\d[\d\s\-()]{8,}\d`),
+}
+
+// defaultRedactions names the builtin patterns applied when none are configured.
+const defaultRedactions = "gstin,pan,email,phone"
+
+// parseRedactions resolves a comma-separated list of builtin pattern names and a
+// whitespace-separated list of extra regular expressions into the patterns to mask.
+func parseRedactions(names, extra string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, name := range splitList(names) {
+		re, ok := builtinRedactions[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction %q", name)
+		}
+		patterns = append(patterns, re)
+	}
+	for _, raw := range strings.Fields(extra) {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", raw, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// redactingHandler is a slog.Handler that masks sensitive text in the message
+// and in every string attribute before passing records on.
+type redactingHandler struct {
+	next     slog.Handler
+	patterns []*regexp.Regexp
+}
+
+// newRedactingHandler wraps next so that matches of patterns never reach it.
+// With no patterns, next is returned as is.
+func newRedactingHandler(next slog.Handler, patterns []*regexp.Regexp) slog.Handler {
+	if len(patterns) == 0 {
+		return next
+	}
+	return &redactingHandler{next: next, patterns: patterns}
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, h.redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactAttr(a)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted), patterns: h.patterns}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), patterns: h.patterns}
+}
+
+// redactAttr masks the value of a, descending into groups. Values of other
+// kinds, such as errors, are masked through their string form when it matches.
+func (h *redactingHandler) redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = h.redactAttr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		s := fmt.Sprint(v.Any())
+		if masked := h.redact(s); masked != s {
+			return slog.String(a.Key, masked)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redact masks every match of the patterns in s.
+func (h *redactingHandler) redact(s string) string {
+	for _, re := range h.patterns {
+		s = re.ReplaceAllString(s, redactionMask)
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// requestIDHeader carries the ID of a request, both from a client or proxy
+// that assigned one and back in the response.
+const requestIDHeader = "X-Request-ID"
+
+// reRequestID is the shape of a request ID accepted from the client. Anything
+// else, which could forge or break up log lines, is replaced by a fresh ID.
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type loggerKey struct{}
+
+// withRequestID assigns every request an ID, taken from its X-Request-ID
+// header when it has a valid one, and otherwise generated. The ID is returned
+// in the X-Request-ID response header and attached to a logger in the request
+// context, which both the handlers, through app.log, and the extractor log to.
+func (app *api) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !reRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		logger := app.logger.With("request_id", id)
+		ctx := context.WithValue(r.Context(), loggerKey{}, logger)
+		ctx = extractor.WithLogger(ctx, logger)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID returns a random 128-bit ID in hex.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// log returns the logger of the request ctx belongs to, which tags every line
+// with its request ID, or the server's logger outside of a request.
+func (app *api) log(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return app.logger
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamResult is the payload of a "result" event of batchStreamHandler: the
+// result of one file, with its position in the upload.
+type streamResult struct {
+	Index int `json:"index"`
+	batchResult
+}
+
+// batchStreamHandler is batchHandler as a stream of Server-Sent Events. Each
+// file sends a "result" event as soon as it is extracted, in completion order,
+// and the stream ends with a "done" event carrying the aggregate of the batch.
+// Request errors are answered with a JSON error before the stream starts.
+func (app *api) batchStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	files, opts, ok := app.readBatch(w, r)
+	if !ok {
+		return
+	}
+
+	// A batch may stream for longer than the write timeout allows a response.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.log(r.Context()).Warn("could not lift the write deadline for the batch stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop proxies such as nginx from buffering events.
+	w.WriteHeader(http.StatusOK)
+
+	var mu sync.Mutex
+	send := func(event string, data any) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			app.log(r.Context()).Error("failed to encode batch event", "event", event, "error", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			// The client went away; the extractions stop with its context.
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			app.log(r.Context()).Error("failed to flush batch event", "error", err)
+		}
+	}
+
+	results := make([]batchResult, len(files))
+	app.extractBatch(r, files, opts, func(i int, res batchResult) {
+		results[i] = res
+		send("result", streamResult{Index: i, batchResult: res})
+	})
+	send("done", aggregateResults(results))
+}
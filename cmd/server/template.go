@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+const (
+	// maxInlineTemplate caps the size of a template sent with the request.
+	maxInlineTemplate = 4 << 10
+	// maxTemplateOutput caps the rendered text, so a template cannot turn a
+	// small result into an arbitrarily large response.
+	maxTemplateOutput = 64 << 10
+)
+
+// errTemplateOutputTooLarge stops a template whose output exceeds maxTemplateOutput.
+var errTemplateOutputTooLarge = fmt.Errorf("output exceeds %d bytes", maxTemplateOutput)
+
+// loadTemplates parses every *.tmpl file in dir into a template named after
+// the file without its extension.
+func loadTemplates(dir string) (map[string]*template.Template, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.tmpl files in %s", dir)
+	}
+	templates := make(map[string]*template.Template, len(paths))
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		if templates[name], err = template.New(name).Parse(string(src)); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// requestTemplate returns the template selected by the request: a named
+// server-side one (?template=) or one sent inline (?template_text=). It returns
+// nil when the request asks for neither. Errors are safe to return to the client.
+func (app *api) requestTemplate(r *http.Request) (*template.Template, error) {
+	query := r.URL.Query()
+	name, text := query.Get("template"), query.Get("template_text")
+	switch {
+	case name != "" && text != "":
+		return nil, errors.New("template and template_text cannot be combined")
+	case name != "":
+		tmpl, ok := app.config.templates[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown template %q", name)
+		}
+		return tmpl, nil
+	case text != "":
+		if len(text) > maxInlineTemplate {
+			return nil, fmt.Errorf("template_text exceeds %d bytes", maxInlineTemplate)
+		}
+		tmpl, err := template.New("inline").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template_text: %w", err)
+		}
+		return tmpl, nil
+	}
+	return nil, nil
+}
+
+// renderTemplate writes details rendered through tmpl as text/plain. A template
+// that fails to execute or produces too much output gets a 400 instead.
+func (app *api) renderTemplate(w http.ResponseWriter, r *http.Request, tmpl *template.Template, details *extractor.InvoiceDetails) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&cappedWriter{w: &buf, n: maxTemplateOutput}, details); err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, "template failed: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		app.log(r.Context()).Error("failed to write rendered template", "error", err)
+	}
+}
+
+// cappedWriter passes at most n bytes on to w and fails once more are written.
+type cappedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if len(p) > c.n {
+		return 0, errTemplateOutputTooLarge
+	}
+	c.n -= len(p)
+	return c.w.Write(p)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// totalsHandler extracts only the amounts and currency of the uploaded invoice.
+// It runs a single text pass, so it answers faster than extractHandler.
+func (app *api) totalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !app.hasDiskSpace(w, r) {
+		return
+	}
+
+	pdf, filename, ok := app.readUpload(w, r)
+	if !ok || !app.requirePDF(w, r, filename, pdf) {
+		return
+	}
+
+	if !app.acquireSlotOrFail(w, r) {
+		return
+	}
+	defer app.releaseSlot()
+
+	totals, err := timedExtraction(app.metrics, func() (*extractor.Totals, error) {
+		return extractor.ExtractTotalsContext(r.Context(), bytes.NewReader(pdf))
+	})
+	if err != nil {
+		app.extractionFailed(w, r, err, filename)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, totals, nil); err != nil {
+		app.log(r.Context()).Error("failed to write totals response", "error", err)
+	}
+}
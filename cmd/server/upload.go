@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxUploadSize bounds the size of an uploaded PDF.
+const maxUploadSize = 10 << 20 // 10MB
+
+// maxFormOverhead is the room a multipart body is given on top of the files it
+// carries, for the boundaries, part headers and other fields.
+const maxFormOverhead = 1 << 20 // 1MB
+
+// readUpload reads the uploaded PDF into memory. It is the request body itself
+// when that is declared as a PDF (see readRawUpload), is taken from a JSON body
+// when the request is JSON (see readJSONUpload), and otherwise from the first
+// of the configured upload fields present in the multipart form.
+// On failure it writes the error response itself and reports false.
+func (app *api) readUpload(w http.ResponseWriter, r *http.Request) (pdf []byte, filename string, ok bool) {
+	if isPDFRequest(r) {
+		return app.readRawUpload(w, r)
+	}
+	if isJSONRequest(r) {
+		return app.readJSONUpload(w, r)
+	}
+
+	if !app.parseMultipartForm(w, r, maxUploadSize) {
+		return nil, "", false
+	}
+
+	headers := app.uploadedFiles(r)
+	if len(headers) == 0 {
+		app.errorResponse(w, r, http.StatusBadRequest, app.missingUploadMessage(r))
+		return nil, "", false
+	}
+	file, err := headers[0].Open()
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, "error retrieving the file from form-data")
+		return nil, "", false
+	}
+	defer file.Close()
+
+	pdf, err = io.ReadAll(file)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, "could not read the uploaded file")
+		return nil, "", false
+	}
+	return pdf, headers[0].Filename, true
+}
+
+// parseMultipartForm parses the multipart body of r, which may carry at most
+// limit bytes of files. The whole body is capped, as the form's files are only
+// kept in memory up to maxUploadSize and would otherwise spill to temporary
+// files without bound. On failure it writes the error response itself, 413
+// when the body is too large, and reports false.
+func (app *api) parseMultipartForm(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+maxFormOverhead)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, "the upload is too large")
+			return false
+		}
+		app.errorResponse(w, r, http.StatusBadRequest, "could not parse multipart form: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// uploadedFiles returns the files of the first configured upload field present in
+// the parsed multipart form, or nil when the client used none of them.
+func (app *api) uploadedFiles(r *http.Request) []*multipart.FileHeader {
+	for _, field := range app.config.uploadFields {
+		if headers := r.MultipartForm.File[field]; len(headers) > 0 {
+			return headers
+		}
+	}
+	return nil
+}
+
+// missingUploadMessage explains which form fields the PDF is expected in, and
+// which file fields the client sent instead, so a misnamed field is easy to spot.
+func (app *api) missingUploadMessage(r *http.Request) string {
+	expected := make([]string, len(app.config.uploadFields))
+	for i, field := range app.config.uploadFields {
+		expected[i] = strconv.Quote(field)
+	}
+	msg := "no file found in form-data; expected the PDF in the " + strings.Join(expected, " or ") + " field"
+
+	var sent []string
+	for field := range r.MultipartForm.File {
+		sent = append(sent, strconv.Quote(field))
+	}
+	if len(sent) > 0 {
+		slices.Sort(sent)
+		msg += ", got " + strings.Join(sent, ", ")
+	}
+	return msg
+}
+
+// readParts reads the PDFs of an extraction request. An invoice split across
+// several files is uploaded as repeated "parts" fields and returned in form
+// order; otherwise the single uploaded file is returned. filename names every part.
+func (app *api) readParts(w http.ResponseWriter, r *http.Request) (pdfs [][]byte, filename string, ok bool) {
+	if isPDFRequest(r) || isJSONRequest(r) {
+		pdf, filename, ok := app.readUpload(w, r)
+		return [][]byte{pdf}, filename, ok
+	}
+
+	if !app.parseMultipartForm(w, r, maxUploadSize) {
+		return nil, "", false
+	}
+
+	headers := r.MultipartForm.File["parts"]
+	if len(headers) == 0 {
+		pdf, filename, ok := app.readUpload(w, r)
+		if !ok {
+			return nil, "", false
+		}
+		return [][]byte{pdf}, filename, true
+	}
+
+	names := make([]string, 0, len(headers))
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, "error retrieving part "+header.Filename+" from form-data")
+			return nil, "", false
+		}
+		pdf, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			app.errorResponse(w, r, http.StatusBadRequest, "could not read part "+header.Filename)
+			return nil, "", false
+		}
+		pdfs = append(pdfs, pdf)
+		names = append(names, header.Filename)
+	}
+	return pdfs, strings.Join(names, "+"), true
+}
+
+// isPDFRequest reports whether the request body is declared as a PDF.
+func isPDFRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/pdf"
+}
+
+// readRawUpload reads a PDF posted as the request body. The filename is taken
+// from a Content-Disposition header, if the client sent one. On failure it
+// writes the error response itself and reports false.
+func (app *api) readRawUpload(w http.ResponseWriter, r *http.Request) (pdf []byte, filename string, ok bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	pdf, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, "the uploaded file is too large")
+			return nil, "", false
+		}
+		app.errorResponse(w, r, http.StatusBadRequest, "could not read the request body")
+		return nil, "", false
+	}
+	if len(pdf) == 0 {
+		app.errorResponse(w, r, http.StatusBadRequest, "the request body is empty; expected the PDF")
+		return nil, "", false
+	}
+
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+	return pdf, filename, true
+}
+
+// jsonUpload is the body of a JSON upload, for clients that cannot send multipart forms.
+type jsonUpload struct {
+	PDFBase64 string `json:"pdf_base64"`
+	Filename  string `json:"filename"`
+}
+
+// isJSONRequest reports whether the request body is declared as JSON.
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// readJSONUpload decodes a jsonUpload body and returns the PDF it carries. The
+// upload size limit applies to the decoded PDF, so the encoded body may be
+// about a third larger. On failure it writes the error response itself and reports false.
+func (app *api) readJSONUpload(w http.ResponseWriter, r *http.Request) (pdf []byte, filename string, ok bool) {
+	// Leave room for the base64 expansion and the rest of the JSON document.
+	r.Body = http.MaxBytesReader(w, r.Body, int64(base64.StdEncoding.EncodedLen(maxUploadSize))+4096)
+
+	var body jsonUpload
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, "the uploaded file is too large")
+			return nil, "", false
+		}
+		app.errorResponse(w, r, http.StatusBadRequest, "could not parse JSON body: "+err.Error())
+		return nil, "", false
+	}
+	if body.PDFBase64 == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, `no file found in the JSON body; expected the PDF in the "pdf_base64" field`)
+		return nil, "", false
+	}
+
+	pdf, err := base64.StdEncoding.DecodeString(body.PDFBase64)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, "pdf_base64 is not valid base64: "+err.Error())
+		return nil, "", false
+	}
+	if len(pdf) > maxUploadSize {
+		app.errorResponse(w, r, http.StatusRequestEntityTooLarge, "the uploaded file is too large")
+		return nil, "", false
+	}
+	return pdf, body.Filename, true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor"
+)
+
+// warmup pre-initializes the Python backend with a trivial extraction so the
+// first real request doesn't pay the cold-start cost. Like any other
+// extraction, it must run while holding a slot taken with acquireSlot, and it
+// stops when ctx is done.
+func (app *api) warmup(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if err := extractor.WarmupContext(ctx); err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+
+	app.warm.Store(true)
+	app.log(ctx).Info("extraction backend warm", "duration_ms", elapsed.Milliseconds())
+	return elapsed, nil
+}
+
+// warmupHandler reports whether the backend is warm on GET. Running a warmup
+// with POST is routed to runWarmupHandler, behind the same protection as the
+// extraction endpoints.
+func (app *api) warmupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET, POST")
+		app.errorResponse(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	status := http.StatusOK
+	if !app.warm.Load() {
+		status = http.StatusServiceUnavailable
+	}
+	if err := app.writeJSON(w, status, map[string]bool{"ready": app.warm.Load()}, nil); err != nil {
+		app.log(r.Context()).Error("failed to write warmup response", "error", err)
+	}
+}
+
+// runWarmupHandler runs a warmup and answers once it completes. Deployment
+// tooling can POST here after a rollout and wait for the 200.
+func (app *api) runWarmupHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.acquireSlotOrFail(w, r) {
+		return
+	}
+	defer app.releaseSlot()
+
+	elapsed, err := app.warmup(r.Context())
+	if err != nil {
+		app.log(r.Context()).Error("warmup failed", "error", err)
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "extraction backend is not available")
+		return
+	}
+	payload := map[string]any{"ready": true, "duration_ms": elapsed.Milliseconds()}
+	if err := app.writeJSON(w, http.StatusOK, payload, nil); err != nil {
+		app.log(r.Context()).Error("failed to write warmup response", "error", err)
+	}
+}
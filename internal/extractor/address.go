@@ -0,0 +1,45 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// addressEnd recognizes where a billing address ends, so the lines printed
+// after it, such as a phone number, are not taken as part of it.
+type addressEnd struct {
+	// terminators holds the upper-cased country names and codes that close an
+	// address, see Config.AddressTerminators.
+	terminators map[string]bool
+	// postalCode matches a postal code, nil when disabled.
+	postalCode *regexp.Regexp
+}
+
+// isTerminator reports whether line closes the address: it is, or ends with
+// after a comma, one of the terminators, e.g. "IN" or "Kolkata, India".
+func (e addressEnd) isTerminator(line string) bool {
+	last := line
+	if i := strings.LastIndexByte(line, ','); i >= 0 {
+		last = line[i+1:]
+	}
+	return e.terminators[strings.ToUpper(strings.TrimSpace(last))]
+}
+
+// trim returns the lines up to and including the first terminator. Without
+// one, it returns the lines up to the last one carrying a postal code, and
+// failing that, all of them.
+func (e addressEnd) trim(lines []string) []string {
+	for i, line := range lines {
+		if e.isTerminator(line) {
+			return lines[:i+1]
+		}
+	}
+	if e.postalCode != nil {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if e.postalCode.MatchString(lines[i]) {
+				return lines[:i+1]
+			}
+		}
+	}
+	return lines
+}
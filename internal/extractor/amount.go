@@ -0,0 +1,180 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// currencyCodes lists the ISO 4217 codes recognised when printed in front of an amount.
+var currencyCodes = []string{"INR", "USD", "EUR", "GBP", "AED", "SGD", "AUD", "CAD", "CHF", "JPY", "CNY"}
+
+// reCurrencyCode finds an ISO currency code immediately followed by an amount,
+// as in "INR 1,234.00" or "USD -50.00".
+var reCurrencyCode = regexp.MustCompile(`\b(` + strings.Join(currencyCodes, "|") + `)\s*[(\-]?\d`)
+
+// reCurrencyCodeSuffix finds an ISO currency code printed right after an
+// amount, as in "1,234.00 USD" or "50.00EUR".
+var reCurrencyCodeSuffix = regexp.MustCompile(`\d\)?\s*(` + strings.Join(currencyCodes, "|") + `)\b`)
+
+// currencySymbols maps the currency symbols recognised in front of an amount to
+// their ISO 4217 code. A bare "$" is taken as US dollars and "¥" as yen.
+var currencySymbols = map[string]string{
+	"\u20B9": "INR", // ₹
+	"Rs.":    "INR",
+	"Rs":     "INR",
+	"US$":    "USD",
+	"$":      "USD",
+	"A$":     "AUD",
+	"S$":     "SGD",
+	"C$":     "CAD",
+	"CA$":    "CAD",
+	"\u20AC": "EUR", // €
+	"\u00A3": "GBP", // £
+	"\u00A5": "JPY", // ¥
+}
+
+// currencySymbolPattern matches the keys of currencySymbols. Longer symbols
+// come first so "US$" is not read as "$".
+const currencySymbolPattern = `\x{20B9}|\bRs\.?|\bUS\$|\bCA\$|\bA\$|\bS\$|\bC\$|\$|\x{20AC}|\x{00A3}|\x{00A5}`
+
+// reCurrencySymbol finds a currency symbol immediately followed by an amount,
+// as in "₹1,234.00", "Rs. 500.00" or "-$50.00".
+var reCurrencySymbol = regexp.MustCompile(`(` + currencySymbolPattern + `)\s*[(\-]?\d`)
+
+// detectCurrencyCode returns the currency of the first amount in text that is
+// printed with an ISO code in front or, failing that, a currency symbol or an
+// ISO code after it, or "" when there is none. Symbols are normalized to their
+// ISO code, e.g. "₹" to "INR".
+func detectCurrencyCode(text string) string {
+	if match := reCurrencyCode.FindStringSubmatch(text); len(match) > 1 {
+		return match[1]
+	}
+	if match := reCurrencySymbol.FindStringSubmatch(text); len(match) > 1 {
+		return currencySymbols[match[1]]
+	}
+	if match := reCurrencyCodeSuffix.FindStringSubmatch(text); len(match) > 1 {
+		return match[1]
+	}
+	return ""
+}
+
+// stripCurrencyCode removes a leading or trailing ISO currency code, or a
+// leading currency symbol, from a printed amount.
+func stripCurrencyCode(s string) string {
+	for _, code := range currencyCodes {
+		if rest, ok := strings.CutPrefix(s, code); ok {
+			return strings.TrimSpace(rest)
+		}
+		if rest, ok := strings.CutSuffix(s, code); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	if loc := reCurrencySymbol.FindStringSubmatchIndex(s); loc != nil && loc[2] == 0 {
+		return strings.TrimSpace(s[loc[3]:])
+	}
+	return s
+}
+
+// parseAmount converts a printed monetary amount into a float64. It accepts
+// Western ("123,456.00") and Indian lakh/crore ("1,23,456.00") digit grouping,
+// with commas or spaces ("1 23 456.00"), without checking it (see
+// validGrouping); an ISO currency code before or after the amount or a leading
+// currency symbol ("INR 1,234.50", "1,234.50 USD", "₹1,234.50"); a leading
+// minus sign ("-1,234.50"); and the accounting notation for negatives
+// ("(1,234.50)"). The second return value is false when s does not hold a
+// number.
+func parseAmount(s string) (float64, bool) {
+	s = stripCurrencyCode(strings.TrimSpace(s))
+	negative := false
+
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	if strings.HasPrefix(s, "-") {
+		negative = !negative
+		s = strings.TrimSpace(s[1:])
+	}
+
+	s = strings.NewReplacer(",", "", " ", "").Replace(s)
+	if s == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		value = -value
+	}
+	return value, true
+}
+
+// Digit grouping styles accepted by validGrouping, see Config.DigitGrouping.
+const (
+	GroupingAny     = ""
+	GroupingWestern = "western"
+	GroupingIndian  = "indian"
+)
+
+var (
+	// reWesternGrouping matches an integer part grouped in thousands: 123,456,789.
+	reWesternGrouping = regexp.MustCompile(`^\d{1,3}(?:,\d{3})*$`)
+	// reIndianGrouping matches an integer part in lakh/crore style, where only the
+	// last group has three digits: 12,34,56,789.
+	reIndianGrouping = regexp.MustCompile(`^\d{1,2}(?:,\d{2})*,\d{3}$`)
+)
+
+// validGrouping reports whether the integer part of a printed amount uses the
+// digit grouping of style, or either style for GroupingAny. Amounts printed
+// without separators are always valid; misplaced commas usually mean OCR noise.
+// Spaces used as separators are checked like commas.
+func validGrouping(printed, style string) bool {
+	s := strings.TrimSpace(stripCurrencyCode(strings.TrimSpace(printed)))
+	s = strings.Trim(s, "()- ")
+	s, _, _ = strings.Cut(s, ".")
+	s = strings.ReplaceAll(s, " ", ",")
+	if !strings.Contains(s, ",") {
+		return true
+	}
+	switch style {
+	case GroupingWestern:
+		return reWesternGrouping.MatchString(s)
+	case GroupingIndian:
+		return reIndianGrouping.MatchString(s)
+	default:
+		return reWesternGrouping.MatchString(s) || reIndianGrouping.MatchString(s)
+	}
+}
+
+// decimalPlaces counts the digits after the decimal point of a printed amount.
+func decimalPlaces(printed string) int {
+	_, frac, ok := strings.Cut(strings.TrimRight(printed, ") "), ".")
+	if !ok {
+		return 0
+	}
+	return len(frac)
+}
+
+// applyPrecision rounds value to the configured amount precision. Printed amounts
+// carrying more decimals than that, or grouped in a way Config.DigitGrouping does
+// not allow, are usually OCR noise, so they are flagged.
+func (d *InvoiceDetails) applyPrecision(field, printed string, value float64) float64 {
+	cfg := activeConfig()
+	if !validGrouping(printed, cfg.DigitGrouping) {
+		d.warn(SeverityError, WarnMalformedAmount, "%s %q has malformed digit grouping", field, printed)
+	}
+	precision := cfg.AmountPrecision
+	if decimalPlaces(printed) > precision {
+		d.warn(SeverityInfo, WarnAmountRounded, "%s %q has more than %d decimal places; rounded", field, printed, precision)
+	}
+	return roundTo(value, precision)
+}
+
+// roundTo rounds value to the given number of decimal places.
+func roundTo(value float64, precision int) float64 {
+	scale := math.Pow10(precision)
+	return math.Round(value*scale) / scale
+}
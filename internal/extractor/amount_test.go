@@ -0,0 +1,104 @@
+package extractor
+
+import "testing"
+
+func TestCurrencyCodes(t *testing.T) {
+	tests := []struct {
+		printed  string
+		currency string
+		value    float64
+	}{
+		{"INR 1,234.00", "INR", 1234},
+		{"USD 50.00", "USD", 50},
+		{"EUR 1,050.50", "EUR", 1050.5},
+		{"INR1,234.00", "INR", 1234},
+		{"USD -50.00", "USD", -50},
+		{"1,234.00 INR", "INR", 1234},
+		{"50.00 USD", "USD", 50},
+		{"1,050.50EUR", "EUR", 1050.5},
+		{"(50.00) USD", "USD", -50},
+		{"₹1,234.00", "INR", 1234},
+		{"Rs. 500.00", "INR", 500},
+		{"US$ 50.00", "USD", 50},
+		{"€1,050.50", "EUR", 1050.5},
+		{"1,234.00", "", 1234},
+	}
+	for _, tt := range tests {
+		if got := detectCurrencyCode(tt.printed); got != tt.currency {
+			t.Errorf("detectCurrencyCode(%q) = %q, want %q", tt.printed, got, tt.currency)
+		}
+		if got, ok := parseAmount(tt.printed); !ok || got != tt.value {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.printed, got, ok, tt.value)
+		}
+	}
+}
+
+// A code only counts when it is printed next to an amount, so words that
+// merely contain one are not taken for a currency.
+func TestDetectCurrencyCodeNeedsAmount(t *testing.T) {
+	for _, text := range []string{"TOTAL INRVOICE 1,234.00", "Paid in USD", "EURO 50.00"} {
+		if got := detectCurrencyCode(text); got != "" {
+			t.Errorf("detectCurrencyCode(%q) = %q, want none", text, got)
+		}
+	}
+}
+
+func TestParseAmountGrouping(t *testing.T) {
+	tests := []struct {
+		printed string
+		want    float64
+	}{
+		{"123,456.00", 123456},
+		{"1,23,456.00", 123456},
+		{"12,34,567.89", 1234567.89},
+		{"1,00,00,000.00", 10000000},
+		{"10,00,00,000.00", 100000000},
+		{"1,000,000.00", 1000000},
+		{"-1,23,456.00", -123456},
+		{"(1,00,000.00)", -100000},
+		{"₹ 1,00,00,000.00", 10000000},
+		{"12,34,5.00", 12345}, // parsed, but see validGrouping
+	}
+	for _, tt := range tests {
+		if got, ok := parseAmount(tt.printed); !ok || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.printed, got, ok, tt.want)
+		}
+	}
+	for _, printed := range []string{"", "abc", "1.2.3", "-"} {
+		if got, ok := parseAmount(printed); ok {
+			t.Errorf("parseAmount(%q) = %v, want no number", printed, got)
+		}
+	}
+}
+
+func TestValidGrouping(t *testing.T) {
+	tests := []struct {
+		printed                 string
+		western, indian, either bool
+	}{
+		{"123,456.00", true, false, true},
+		{"1,234,567.00", true, false, true},
+		{"1,23,456.00", false, true, true},
+		{"12,34,567.00", false, true, true},
+		{"1,00,00,000.00", false, true, true},
+		{"10,000.00", true, true, true}, // both styles agree below a lakh
+		{"1234567.00", true, true, true},
+		{"12,34,5.00", false, false, false},
+		{"1,2345.00", false, false, false},
+		{"123,45,678.00", false, false, false},
+		{",123.00", false, false, false},
+		{"-1,23,456.00", false, true, true},
+		{"(1,00,000.00)", false, true, true},
+		{"INR 1,00,000.00", false, true, true},
+	}
+	for _, tt := range tests {
+		for _, c := range []struct {
+			style string
+			want  bool
+		}{{GroupingWestern, tt.western}, {GroupingIndian, tt.indian}, {GroupingAny, tt.either}} {
+			if got := validGrouping(tt.printed, c.style); got != c.want {
+				t.Errorf("validGrouping(%q, %q) = %v, want %v", tt.printed, c.style, got, c.want)
+			}
+		}
+	}
+}
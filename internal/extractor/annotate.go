@@ -0,0 +1,94 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// AnnotatorScript is the script that renders extracted fields onto a copy of
+// the PDF. It is looked up next to the text extraction script (Config.ScriptPath).
+const AnnotatorScript = "pdf_annotator.py"
+
+// Annotate returns a copy of pdf with a summary page of the extracted details appended,
+// so a reviewer can check the values against the original document.
+//
+// The rendering is done by AnnotatorScript, invoked as
+//
+//	python pdf_annotator.py <input.pdf> <fields.json> <output.pdf>
+//
+// where fields.json is a JSON array of [label, value] pairs in display order.
+func Annotate(pdf []byte, details *InvoiceDetails) ([]byte, error) {
+	dir, err := os.MkdirTemp("", tempAnnotatePattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fields, err := json.Marshal(summaryFields(details))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fields: %w", err)
+	}
+
+	inputPath := filepath.Join(dir, "input.pdf")
+	fieldsPath := filepath.Join(dir, "fields.json")
+	outputPath := filepath.Join(dir, "output.pdf")
+	if err := os.WriteFile(inputPath, pdf, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write temp pdf: %w", err)
+	}
+	if err := os.WriteFile(fieldsPath, fields, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write temp fields: %w", err)
+	}
+
+	cfg := activeConfig()
+	scriptPath := filepath.Join(filepath.Dir(cfg.scriptPath), AnnotatorScript)
+	cmd := exec.Command(cfg.PythonPath, scriptPath, inputPath, fieldsPath, outputPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("annotator script failed: %w. Stderr: %s", err, stderr.String())
+	}
+
+	annotated, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotated pdf: %w", err)
+	}
+	return annotated, nil
+}
+
+// summaryFields lists the populated scalar fields of d as [label, value] pairs,
+// in struct order, using the JSON field names as labels.
+func summaryFields(d *InvoiceDetails) [][2]string {
+	var fields [][2]string
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		var value string
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			value = f.String()
+		case reflect.Float64:
+			if f.Float() != 0 {
+				value = strconv.FormatFloat(f.Float(), 'f', 2, 64)
+			}
+		case reflect.Bool:
+			if f.Bool() {
+				value = "yes"
+			}
+		}
+		if value != "" {
+			fields = append(fields, [2]string{name, value})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,62 @@
+package extractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGSTLabelPattern(t *testing.T) {
+	re := gstLabelPattern(DefaultConfig().GSTLabels)
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"GST Registration No: 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GSTIN No. 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GSTIN: 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GSTIN 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GST No - 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GST Number:27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"gstin : 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"GST   Registration   No : 27AAPFU0939F1ZV", "27AAPFU0939F1ZV"},
+		{"12 MG Road, Bengaluru", ""},
+	}
+	for _, tt := range tests {
+		if got := findStringSubmatchAndClean(re, tt.line, 1); got != tt.want {
+			t.Errorf("GST label in %q captured %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseBillingBlockGSTLabels(t *testing.T) {
+	cfg := activeConfig()
+	for _, label := range []string{"GST Registration No:", "GSTIN:", "GSTIN No.", "GST No:", "GST Number -", "gstin"} {
+		t.Run(label, func(t *testing.T) {
+			block := "Bill To:\nBuyer Pvt Ltd\n12 MG Road\nBengaluru\n" + label + " 29AAGCB7383J1Z4\n"
+			name, address, gst := parseBillingBlock(block, cfg.gstLabel, cfg.billingLabel, cfg.addressEnd)
+			if name != "Buyer Pvt Ltd" {
+				t.Errorf("name = %q, want %q", name, "Buyer Pvt Ltd")
+			}
+			if want := []string{"12 MG Road", "Bengaluru"}; !slices.Equal(address, want) {
+				t.Errorf("address = %q, want %q", address, want)
+			}
+			if gst != "29AAGCB7383J1Z4" {
+				t.Errorf("gst = %q, want %q", gst, "29AAGCB7383J1Z4")
+			}
+		})
+	}
+}
+
+// Only the configured labels mark the GST line; others stay in the address.
+func TestParseBillingBlockCustomGSTLabel(t *testing.T) {
+	cfg := activeConfig()
+	re := gstLabelPattern([]string{"Tax ID"})
+	block := "Buyer Pvt Ltd\nTax ID: 29AAGCB7383J1Z4\nGSTIN: 27AAPFU0939F1ZV\n"
+	name, address, gst := parseBillingBlock(block, re, cfg.billingLabel, cfg.addressEnd)
+	if name != "Buyer Pvt Ltd" || gst != "29AAGCB7383J1Z4" {
+		t.Errorf("name, gst = %q, %q, want %q, %q", name, gst, "Buyer Pvt Ltd", "29AAGCB7383J1Z4")
+	}
+	if want := []string{"GSTIN: 27AAPFU0939F1ZV"}; !slices.Equal(address, want) {
+		t.Errorf("address = %q, want %q", address, want)
+	}
+}
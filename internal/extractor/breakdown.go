@@ -0,0 +1,64 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	reSubtotal = regexp.MustCompile(`(?im)^.*?\b(?:Sub\s*-?\s*Total|Taxable\s+(?:Value|Amount)|(?:Total|Amount)\s+Before\s+Tax)\b[ \t]*:?(.*)$`)
+	reDiscount = regexp.MustCompile(`(?im)^.*?\bDiscount\b[ \t]*:?(.*)$`)
+	reRoundOff = regexp.MustCompile(`(?im)^.*?\b(Less[ \t]*:?[ \t]*)?(?:Round(?:ed|ing)?\s*-?\s*Off|Rounding)\b[ \t]*:?(.*)$`)
+)
+
+// parseBreakdown reads the subtotal, discount and round-off printed between the
+// item table and the total. The subtotal and discount are absolute values; the
+// round-off keeps its sign, which invoices print as "-0.40", "(-) 0.40" or by
+// labelling it "Less: Round Off". Each stays nil when it is not printed.
+func (d *InvoiceDetails) parseBreakdown(text string) {
+	if v, ok := lastLabelledAmount(reSubtotal, text); ok {
+		d.Subtotal = &v
+	}
+	if v, ok := lastLabelledAmount(reDiscount, text); ok {
+		d.Discount = &v
+	}
+
+	matches := reRoundOff.FindAllStringSubmatch(text, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		amounts := reAmount.FindAllString(matches[i][2], -1)
+		if len(amounts) == 0 {
+			continue
+		}
+		v, ok := parseAmount(amounts[len(amounts)-1])
+		if !ok {
+			continue
+		}
+		if v > 0 && (matches[i][1] != "" || strings.Contains(matches[i][2], "(-)")) {
+			v = -v
+		}
+		v = roundTo(v, activeConfig().AmountPrecision)
+		if v == 0 {
+			v = 0 // not -0, from a printed "-0.00"
+		}
+		d.RoundOff = &v
+		return
+	}
+}
+
+// breakdownTotal returns subtotal + tax + round-off - discount, the total the
+// printed breakdown implies, and whether a subtotal was printed to compute it.
+// A breakdown that omits the tax, discount or round-off counts it as zero.
+func (d *InvoiceDetails) breakdownTotal() (float64, bool) {
+	if d.Subtotal == nil {
+		return 0, false
+	}
+	total := *d.Subtotal + math.Abs(d.TaxAmountValue)
+	if d.RoundOff != nil {
+		total += *d.RoundOff
+	}
+	if d.Discount != nil {
+		total -= *d.Discount
+	}
+	return total, true
+}
@@ -0,0 +1,120 @@
+package extractor
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// results caches extraction results by the content of the PDF and the options
+// that shape them. See Config.CacheSize.
+var results = &resultCache{
+	entries:  make(map[string]*list.Element),
+	inflight: make(map[string]*inflightCall),
+	order:    list.New(),
+}
+
+// cacheKey identifies a result: the SHA-256 of the PDF bytes, the options that
+// change what is extracted and the configuration it runs with. Strict and
+// SoftTimeout are left out; strict mode is applied to the cached result and
+// partial results are never cached.
+func cacheKey(sum string, opts Options) string {
+	// Maps print with their keys sorted, and %q quotes the patterns, so equal
+	// custom fields, and only those, share a key.
+	return fmt.Sprintf("%s|%v|%t|%t|%s|%q|%s", sum, opts.OCRPages, opts.MatchedBy, opts.RawText, opts.TextSource, opts.CustomFields, activeConfig().version)
+}
+
+// errExtractionAborted is returned to callers that waited on an extraction
+// that did not complete.
+var errExtractionAborted = errors.New("extraction aborted")
+
+// resultCache is a least-recently-used cache of extraction results that also
+// coalesces concurrent extractions of the same key into one.
+type resultCache struct {
+	mu       sync.Mutex
+	entries  map[string]*list.Element // Values are *cacheEntry.
+	order    *list.List               // Most recently used first.
+	inflight map[string]*inflightCall
+}
+
+type cacheEntry struct {
+	key     string
+	details *InvoiceDetails
+}
+
+// inflightCall is an extraction in progress that later callers wait for.
+type inflightCall struct {
+	done    chan struct{}
+	details *InvoiceDetails
+	err     error
+	// abandoned is set when the extraction ended because the context of the
+	// caller running it was done, which says nothing of the waiters' own.
+	abandoned bool
+}
+
+// get returns the cached result for key, or runs extract to produce it. While
+// extract runs, other callers asking for key wait for its result rather than
+// starting their own. Errors and partial results are not cached. With caching
+// disabled, extract is simply called. A waiter whose ctx is done stops waiting;
+// one whose ctx is still live when the caller it waited on went away tries
+// again, extracting itself if no one else has started.
+func (c *resultCache) get(ctx context.Context, key string, extract func() (*InvoiceDetails, error)) (*InvoiceDetails, error) {
+	size := activeConfig().CacheSize
+	if size <= 0 {
+		return extract()
+	}
+
+	for {
+		c.mu.Lock()
+		if el, ok := c.entries[key]; ok {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			return el.Value.(*cacheEntry).details, nil
+		}
+		call, ok := c.inflight[key]
+		if !ok {
+			break // Still locked; this caller extracts.
+		}
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			if call.abandoned && ctx.Err() == nil {
+				continue
+			}
+			return call.details, call.err
+		case <-ctx.Done():
+			return nil, timeoutError(ctx, ctx.Err())
+		}
+	}
+
+	// Waiters see this error should extract panic.
+	call := &inflightCall{done: make(chan struct{}), err: errExtractionAborted}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil && call.details != nil && !call.details.Partial {
+			c.add(key, call.details, size)
+		}
+		call.abandoned = call.err != nil && ctx.Err() != nil
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.details, call.err = extract()
+	return call.details, call.err
+}
+
+// add stores details under key and evicts the least recently used entries
+// beyond size. c.mu must be held.
+func (c *resultCache) add(key string, details *InvoiceDetails, size int) {
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, details: details})
+	for c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
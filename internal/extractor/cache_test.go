@@ -0,0 +1,57 @@
+package extractor
+
+import (
+	"container/list"
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestCache() *resultCache {
+	return &resultCache{
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*inflightCall),
+		order:    list.New(),
+	}
+}
+
+// A waiter must not inherit the cancellation of the caller it waited on.
+func TestResultCacheWaiterRetriesAfterLeaderCancel(t *testing.T) {
+	if activeConfig().CacheSize <= 0 {
+		t.Skip("cache disabled by default configuration")
+	}
+	c := newTestCache()
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	leaderDone := make(chan error)
+	go func() {
+		_, err := c.get(leaderCtx, "key", func() (*InvoiceDetails, error) {
+			close(started)
+			<-leaderCtx.Done()
+			return nil, leaderCtx.Err()
+		})
+		leaderDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan *InvoiceDetails)
+	go func() {
+		details, err := c.get(context.Background(), "key", func() (*InvoiceDetails, error) {
+			return &InvoiceDetails{InvoiceNumber: "INV-1"}, nil
+		})
+		if err != nil {
+			t.Errorf("waiter got error %v, want its own extraction", err)
+		}
+		waiterDone <- details
+	}()
+
+	time.Sleep(10 * time.Millisecond) // Let the waiter join the extraction.
+	cancelLeader()
+	if err := <-leaderDone; err == nil {
+		t.Error("cancelled caller got no error")
+	}
+	if details := <-waiterDone; details == nil || details.InvoiceNumber != "INV-1" {
+		t.Errorf("waiter got %+v, want the result of its own extraction", details)
+	}
+}
@@ -0,0 +1,39 @@
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// reCIN matches the structure of a Corporate Identification Number: listing
+// status, industry code, state, year of incorporation, ownership and
+// registration number, as in "U72200KA2010PTC052345".
+var reCIN = regexp.MustCompile(`\b([LU])(\d{5})([A-Z]{2})(\d{4})([A-Z]{3})(\d{6})\b`)
+
+// cinOwnership lists the ownership codes a CIN may carry.
+var cinOwnership = map[string]bool{
+	"PLC": true, // Public limited company
+	"PTC": true, // Private limited company
+	"OPC": true, // One person company
+	"GOI": true, // Company owned by the Government of India
+	"SGC": true, // State government company
+	"FLC": true, // Financial lease company
+	"FTC": true, // Subsidiary of a foreign company
+	"GAP": true, // Public company limited by guarantee
+	"GAT": true, // Private company limited by guarantee
+	"NPL": true, // Not-for-profit licensed company
+	"ULL": true, // Public unlimited company
+	"ULT": true, // Private unlimited company
+}
+
+// findCIN returns the first structurally valid CIN in text, or "".
+func findCIN(text string) string {
+	for _, m := range reCIN.FindAllStringSubmatch(text, -1) {
+		year, _ := strconv.Atoi(m[4])
+		if cinOwnership[m[5]] && year >= 1850 && year <= time.Now().Year() {
+			return m[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fieldPattern pairs a field with the pattern that extracts it and where the
+// value is stored.
+type fieldPattern struct {
+	field string
+	dst   *string
+	re    *regexp.Regexp
+}
+
+// combineTexts joins the simple and column layouts into one text: the simple
+// lines, followed by the column lines that do not already appear among them.
+// Patterns run against it can pair a label from one layout with a value that
+// only the other layout kept next to it.
+func combineTexts(simple, columns string) string {
+	seen := make(map[string]bool)
+	var b strings.Builder
+	for _, text := range []string{simple, columns} {
+		for _, line := range strings.Split(text, "\n") {
+			key := strings.Join(strings.Fields(line), " ")
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// fillFromCombined retries each field still empty after its primary layout
+// against the combined text. Recovered values that fail the field's format
+// check are recorded as ambiguities.
+func (d *InvoiceDetails) fillFromCombined(combined string, fields []fieldPattern) {
+	for _, f := range fields {
+		if *f.dst != "" {
+			continue
+		}
+		d.match(f.field, f.dst, f.re, combined)
+		if *f.dst != "" {
+			d.doubt(f.field, doubtOffPosition)
+		}
+		if valid := fieldValidators[f.field]; *f.dst != "" && valid != nil && !valid(*f.dst) {
+			d.ambiguous("%s %q is not in the expected format", f.field, *f.dst)
+		}
+	}
+}
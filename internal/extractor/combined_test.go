@@ -0,0 +1,44 @@
+package extractor
+
+import "testing"
+
+func TestCombineTexts(t *testing.T) {
+	simple := "Invoice Number: INV-1\nOrder Number:\n"
+	columns := "Invoice  Number:  INV-1\nOD-4471     Widget\n\n"
+	want := "Invoice Number: INV-1\nOrder Number:\nOD-4471     Widget\n"
+	if got := combineTexts(simple, columns); got != want {
+		t.Errorf("combineTexts() = %q, want %q", got, want)
+	}
+}
+
+// The simple layout keeps the "Order Number" label but moves its value away,
+// and the column layout keeps the value without the label. Only the combined
+// text pairs them up.
+func TestFillFromCombinedRecoversField(t *testing.T) {
+	simple := "TAX INVOICE\nInvoice Number: INV-1\nOrder Number:"
+	columns := "TAX INVOICE\nInvoice Number: INV-1\nOD-4471     Widget"
+
+	d := &InvoiceDetails{}
+	d.match("order_number", &d.OrderNumber, reOrderNo, simple)
+	if d.OrderNumber == "" {
+		d.match("order_number", &d.OrderNumber, reOrderNo, columns)
+	}
+	if d.OrderNumber != "" {
+		t.Fatalf("order number %q found without the combined text", d.OrderNumber)
+	}
+	d.InvoiceNumber = "INV-1"
+
+	d.fillFromCombined(combineTexts(simple, columns), d.fieldRulePatterns())
+	if d.OrderNumber != "OD-4471" {
+		t.Errorf("OrderNumber = %q, want %q", d.OrderNumber, "OD-4471")
+	}
+	if d.InvoiceNumber != "INV-1" {
+		t.Errorf("InvoiceNumber = %q, fields found in their own layout must be kept", d.InvoiceNumber)
+	}
+	if d.doubts["order_number"] == 0 {
+		t.Error("a value recovered from the combined text was not doubted")
+	}
+	if d.doubts["invoice_number"] != 0 {
+		t.Error("a field found in its own layout was doubted")
+	}
+}
@@ -0,0 +1,82 @@
+package extractor
+
+import "math"
+
+// How much each doubt about a value lowers its confidence, from a starting
+// score of 1. A clean labelled match confirmed by both layouts keeps 1.
+const (
+	// doubtSingleSource applies to values that only one layout produced,
+	// whether by design or because the other did not find the field.
+	doubtSingleSource = 0.15
+	// doubtOffPosition applies to values found away from where the field is
+	// expected: a label and value joined across the two layouts, or a client
+	// GSTIN found outside the billing block.
+	doubtOffPosition = 0.15
+	// doubtMultipleMatches applies when the layout a value was read from
+	// carried another, different value for the field.
+	doubtMultipleMatches = 0.25
+	// doubtLayoutConflict applies when the two layouts disagreed.
+	doubtLayoutConflict = 0.4
+	// doubtHeuristic applies to values inferred from their shape rather than
+	// read from a label, see InvoiceDetails.HeuristicFields.
+	doubtHeuristic = 0.4
+	// doubtInvalidFormat applies to values that fail their format check.
+	doubtInvalidFormat = 0.5
+)
+
+// formatChecks check the format of the fields whose shape is known, for
+// confidence scoring. They cover more fields than fieldValidators, which only
+// settle conflicts between the layouts.
+var formatChecks = map[string]func(d *InvoiceDetails) bool{
+	"invoice_date":  func(d *InvoiceDetails) bool { return validDate(d.InvoiceDate) },
+	"order_date":    func(d *InvoiceDetails) bool { return validDate(d.OrderDate) },
+	"state_code":    func(d *InvoiceDetails) bool { return validStateCode(d.StateCode) },
+	"gst_no_client": func(d *InvoiceDetails) bool { return ValidateGSTIN(d.GSTNOClient) },
+	"tax_amount":    func(d *InvoiceDetails) bool { return validPrintedAmount(d.TaxAmount) },
+	"total_amount":  func(d *InvoiceDetails) bool { return validPrintedAmount(d.TotalAmount) },
+	"place_of_supply": func(d *InvoiceDetails) bool {
+		return d.PlaceOfSupply.Code != "" && d.PlaceOfSupply.Name != ""
+	},
+}
+
+func validPrintedAmount(s string) bool {
+	_, ok := parseAmount(s)
+	return ok && validGrouping(s, activeConfig().DigitGrouping)
+}
+
+// doubt lowers the confidence of field by amount.
+func (d *InvoiceDetails) doubt(field string, amount float64) {
+	if d.doubts == nil {
+		d.doubts = make(map[string]float64)
+	}
+	d.doubts[field] += amount
+}
+
+// confirm records that both layouts produced the value of field.
+func (d *InvoiceDetails) confirm(field string) {
+	if d.confirmed == nil {
+		d.confirmed = make(map[string]bool)
+	}
+	d.confirmed[field] = true
+}
+
+// scoreConfidence sets Confidence for every field read from the document, from
+// the doubts recorded while parsing, whether both layouts confirmed the value,
+// and whether it passes its format check. Scores are rounded to two decimals
+// and never drop below 0.
+func (d *InvoiceDetails) scoreConfidence() {
+	d.Confidence = make(map[string]float64)
+	for _, f := range d.documentFields() {
+		if !f.found {
+			continue
+		}
+		score := 1 - d.doubts[f.name]
+		if !d.confirmed[f.name] {
+			score -= doubtSingleSource
+		}
+		if check := formatChecks[f.name]; check != nil && !check(d) {
+			score -= doubtInvalidFormat
+		}
+		d.Confidence[f.name] = math.Max(0, roundTo(score, 2))
+	}
+}
@@ -0,0 +1,482 @@
+package extractor
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds the deployment-wide extraction settings. It is installed once at
+// startup with Configure and applies to every extraction that follows.
+type Config struct {
+	// PythonPath is the Python interpreter that runs the text extraction
+	// script at ScriptPath. Relative paths are taken from the working
+	// directory; see ResolvePythonPaths to check and pin them at startup.
+	PythonPath string
+	ScriptPath string
+
+	// DefaultCountryCode is the calling code, without the "+", assumed for
+	// phone numbers printed without one.
+	DefaultCountryCode string
+
+	// SellerGSTINs lists the seller's own GST numbers. They are never
+	// attributed to the client, and are always labelled as the seller's.
+	SellerGSTINs []string
+
+	// TotalLabels lists the labels that introduce the document total, most
+	// specific first. The first label present in the document wins.
+	TotalLabels []string
+
+	// InvoiceNumberShape is a regular expression for invoice numbers printed
+	// without an "Invoice Number" label. When the label is missing, the first
+	// match within the top InvoiceNumberSearchLines lines of the document is
+	// taken as the invoice number, flagged as a heuristic match. Empty disables
+	// the fallback.
+	InvoiceNumberShape       string
+	InvoiceNumberSearchLines int
+
+	// ProductCodeLabels lists the labels that introduce a product code ("ASIN",
+	// "SKU", "Item Code"), and ProductCodePattern the shape of the code that
+	// follows. Together they drive InvoiceDetails.ASN.
+	ProductCodeLabels  []string
+	ProductCodePattern string
+
+	// GSTLabels lists the labels that introduce the client's GSTIN inside the
+	// billing block, e.g. "GSTIN" or "GST No". Lines carrying one are read as the
+	// client's GSTIN and kept out of the billing address.
+	GSTLabels []string
+
+	// BillingLabels lists labels such as "Bill To" that may open the billing
+	// block. A label standing alone on a line, or followed by a colon or dash,
+	// is stripped so the billing name is not captured as a label.
+	BillingLabels []string
+
+	// AddressTerminators lists the country names and codes, such as "IN" or
+	// "India", that close the billing address when a line is, or ends with,
+	// one of them. PostalCodePattern is a regular expression for postal codes;
+	// without a terminator, the address ends at the last line carrying one.
+	// Empty disables the postal code check. Failing both, every line of the
+	// billing block is kept.
+	AddressTerminators []string
+	PostalCodePattern  string
+
+	// AddressLines additionally returns the billing address as its printed
+	// lines in InvoiceDetails.BillingAddressLines.
+	AddressLines bool
+
+	// DateLayout, when set, is a Go time layout (e.g. "02 Jan 2006") used to
+	// render the extracted dates into the *_formatted fields.
+	DateLayout string
+
+	// DateOrder is the order in which the day and month of numeric dates are
+	// read: DateOrderDayFirst (the default when empty), DateOrderMonthFirst,
+	// or DateOrderAuto to tell them apart per date. It applies to the ISO and
+	// formatted dates and to the fiscal period.
+	DateOrder string
+
+	// AmountPrecision is the number of decimals numeric amounts are rounded to.
+	AmountPrecision int
+
+	// DigitGrouping is the thousands grouping printed amounts are expected to
+	// use: GroupingWestern, GroupingIndian or GroupingAny for either. Amounts
+	// grouped otherwise are flagged as likely OCR errors.
+	DigitGrouping string
+
+	// FieldRules maps labelled fields (see RuleFields) to the regular
+	// expressions tried, in order, to read them, replacing the built-in
+	// patterns. Each must capture the value in its first group. See LoadRules.
+	FieldRules map[string][]string
+
+	// CustomFields maps the names of fields the extractor does not model, such
+	// as a due date or payment terms, to the regular expression that reads
+	// each into InvoiceDetails.CustomFields. See ValidateCustomFields.
+	CustomFields map[string]string
+
+	// IDRules maps ID fields (see IDFields) to how they are normalized into
+	// InvoiceDetails.NormalizedIDs. Fields without a rule are not normalized.
+	IDRules map[string]IDRule
+
+	// FiscalYearStartMonth is the month the fiscal year begins in, used to derive
+	// the fiscal year and quarter of the invoice date.
+	FiscalYearStartMonth time.Month
+
+	// MaxTextSize caps, in bytes, the text of each layout handed to the parser.
+	// Longer text is truncated with a warning. Zero disables the cap.
+	MaxTextSize int
+
+	// MaxPages is the most pages a document may have. Longer documents are
+	// rejected with ErrTooManyPages before any text is extracted, their pages
+	// being counted from the page tree without rendering them. Zero disables
+	// the check.
+	MaxPages int
+
+	// MaxNotesSize caps, in bytes, the notes captured into InvoiceDetails.Notes,
+	// so a full terms-and-conditions page is not copied. Zero disables notes.
+	MaxNotesSize int
+
+	// Engines lists the libraries tried, in order, to read the text layer until
+	// one yields usable text. See EnginePDFPlumber and its siblings.
+	Engines []string
+
+	// OCRFallbackChars is the number of letters and digits below which the
+	// text layer is taken to be missing, as in a scanned PDF, and the pages
+	// are read through OCR instead (see TextSourceAuto). Zero disables the
+	// fallback.
+	OCRFallbackChars int
+
+	// CombinedText retries the single-line fields that came up empty in their
+	// layout against the simple and column text combined.
+	CombinedText bool
+
+	// MinPopulatedFields is how many fields must be read from the document for
+	// the result to count as extracted (see InvoiceDetails.Extracted).
+	MinPopulatedFields int
+
+	// PythonWorkers is how many long-lived Python processes serve text
+	// extraction, amortizing interpreter start-up and library imports. Zero
+	// starts a fresh process for every pass.
+	PythonWorkers int
+
+	// Timeout caps the time an extraction may take. When it elapses the Python
+	// processes still running are killed and the extraction fails with
+	// ErrExtractionTimeout. Zero leaves extractions unbounded.
+	Timeout time.Duration
+
+	// CacheSize is how many extraction results are kept in memory, keyed by the
+	// SHA-256 of the PDF, so re-uploads skip the Python passes. Zero disables
+	// the cache.
+	CacheSize int
+
+	// Validate runs InvoiceDetails.Validate on every result and reports its
+	// failures in the response.
+	Validate bool
+}
+
+// DefaultConfig returns the settings used when Configure is never called.
+func DefaultConfig() Config {
+	return Config{
+		PythonPath:         DefaultPythonPath,
+		ScriptPath:         DefaultScriptPath,
+		DefaultCountryCode: "91",
+		SellerGSTINs:       []string{"19APGPS1824K1ZI"},
+		DateOrder:          DateOrderDayFirst,
+		AmountPrecision:    2,
+		MaxTextSize:        1 << 20,
+		MaxPages:           200,
+		MinPopulatedFields: 1,
+		OCRFallbackChars:   minUsableChars,
+		MaxNotesSize:       500,
+		CacheSize:          256,
+		Engines:            []string{EnginePDFPlumber},
+		// The Indian financial year runs April to March.
+		FiscalYearStartMonth: time.April,
+		TotalLabels: []string{
+			"Grand Total",
+			"Invoice Total",
+			"Total Amount",
+			"Net Amount",
+			"Amount Payable",
+			"Total",
+		},
+		InvoiceNumberShape:       `\b(?:INV|BILL)[-/]?\d[A-Z0-9/\-]*\b`,
+		InvoiceNumberSearchLines: 15,
+		BillingLabels: []string{
+			"Bill To",
+			"Billed To",
+			"Billing To",
+			"Customer Name",
+			"Customer",
+			"Buyer",
+			"Sold To",
+			"Name",
+		},
+		ProductCodeLabels: []string{
+			"ASIN",
+			"ASN",
+			"FSN",
+			"SKU",
+			"Item Code",
+			"Product Code",
+			"Article No",
+		},
+		ProductCodePattern: `[A-Z0-9][A-Z0-9\-]{3,19}`,
+		AddressTerminators: []string{"IN", "India", "CA", "Canada"},
+		// Indian PIN codes, printed as "700001" or "700 001".
+		PostalCodePattern: `\b[1-9]\d{2}\s?\d{3}\b`,
+		GSTLabels: []string{
+			"GST Registration No",
+			"GSTIN No",
+			"GSTIN",
+			"GST No",
+			"GST Number",
+		},
+	}
+}
+
+// compiledConfig is a Config together with the patterns derived from it,
+// so they are compiled once per configuration rather than once per extraction.
+type compiledConfig struct {
+	Config
+	totalLabels []*regexp.Regexp
+	gstLabel    *regexp.Regexp
+	// billingLabel matches a billing label at the start of a line, nil when
+	// no labels are configured.
+	billingLabel *regexp.Regexp
+	// productCode captures a labelled product code, nil when no labels are configured.
+	productCode *regexp.Regexp
+	// invoiceNumberShape is the compiled InvoiceNumberShape, nil when disabled.
+	invoiceNumberShape *regexp.Regexp
+	// fieldRules is the compiled FieldRules.
+	fieldRules map[string][]*regexp.Regexp
+	// customFields is the compiled CustomFields.
+	customFields map[string]*regexp.Regexp
+	// addressEnd is the compiled AddressTerminators and PostalCodePattern.
+	addressEnd addressEnd
+	// scriptPath is ScriptPath made absolute.
+	scriptPath string
+	// sellerGSTINs holds SellerGSTINs in upper case, for lookup.
+	sellerGSTINs map[string]bool
+	// version fingerprints the Config, see InvoiceDetails.ConfigVersion.
+	version string
+}
+
+// current is the active configuration, swapped atomically so extractions in
+// flight always see a consistent snapshot.
+var current atomic.Pointer[compiledConfig]
+
+func init() {
+	cc, err := compile(DefaultConfig())
+	if err != nil {
+		panic("extractor: invalid default config: " + err.Error())
+	}
+	current.Store(cc)
+}
+
+// Configure validates cfg and installs it for all subsequent extractions.
+// The active configuration is left untouched when cfg is invalid.
+func Configure(cfg Config) error {
+	cc, err := compile(cfg)
+	if err != nil {
+		return err
+	}
+	current.Store(cc)
+	return nil
+}
+
+// compile validates cfg and derives the patterns it implies.
+func compile(cfg Config) (*compiledConfig, error) {
+	if !isDigits(cfg.DefaultCountryCode) || len(cfg.DefaultCountryCode) > 3 {
+		return nil, fmt.Errorf("default country code %q must be 1-3 digits", cfg.DefaultCountryCode)
+	}
+
+	cc := &compiledConfig{Config: cfg, version: configVersion(cfg)}
+
+	if cfg.PythonPath == "" || cfg.ScriptPath == "" {
+		return nil, fmt.Errorf("the python interpreter and script paths are required")
+	}
+	scriptPath, err := filepath.Abs(filepath.FromSlash(cfg.ScriptPath))
+	if err != nil {
+		return nil, fmt.Errorf("script path %q: %w", cfg.ScriptPath, err)
+	}
+	cc.scriptPath = scriptPath
+
+	cc.sellerGSTINs = make(map[string]bool, len(cfg.SellerGSTINs))
+	for _, number := range cfg.SellerGSTINs {
+		number = strings.ToUpper(strings.TrimSpace(number))
+		if !reGSTINToken.MatchString(number) || len(number) != 15 {
+			return nil, fmt.Errorf("seller GSTIN %q is not a valid GSTIN", number)
+		}
+		cc.sellerGSTINs[number] = true
+	}
+
+	if len(cfg.TotalLabels) == 0 {
+		return nil, fmt.Errorf("at least one total label is required")
+	}
+	for _, label := range cfg.TotalLabels {
+		if strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("total labels must not be blank")
+		}
+		re, err := totalLabelPattern(label)
+		if err != nil {
+			return nil, fmt.Errorf("total label %q: %w", label, err)
+		}
+		cc.totalLabels = append(cc.totalLabels, re)
+	}
+
+	if len(cfg.GSTLabels) == 0 {
+		return nil, fmt.Errorf("at least one GST label is required")
+	}
+	for _, label := range cfg.GSTLabels {
+		if strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("GST labels must not be blank")
+		}
+	}
+	cc.gstLabel = gstLabelPattern(cfg.GSTLabels)
+
+	for _, label := range cfg.BillingLabels {
+		if strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("billing labels must not be blank")
+		}
+	}
+	if len(cfg.BillingLabels) > 0 {
+		cc.billingLabel = regexp.MustCompile(`(?i)^(?:` + labelAlternation(cfg.BillingLabels) + `)\s*(?:[:\-]\s*|$)`)
+	}
+
+	cc.addressEnd.terminators = make(map[string]bool, len(cfg.AddressTerminators))
+	for _, term := range cfg.AddressTerminators {
+		term = strings.ToUpper(strings.TrimSpace(term))
+		if term == "" {
+			return nil, fmt.Errorf("address terminators must not be blank")
+		}
+		cc.addressEnd.terminators[term] = true
+	}
+	if cfg.PostalCodePattern != "" {
+		re, err := regexp.Compile(cfg.PostalCodePattern)
+		if err != nil {
+			return nil, fmt.Errorf("postal code pattern: %w", err)
+		}
+		cc.addressEnd.postalCode = re
+	}
+
+	if len(cfg.ProductCodeLabels) > 0 {
+		for _, label := range cfg.ProductCodeLabels {
+			if strings.TrimSpace(label) == "" {
+				return nil, fmt.Errorf("product code labels must not be blank")
+			}
+		}
+		value, err := regexp.Compile(cfg.ProductCodePattern)
+		if err != nil {
+			return nil, fmt.Errorf("product code pattern: %w", err)
+		}
+		if value.NumSubexp() > 0 {
+			return nil, fmt.Errorf("product code pattern must not have capture groups")
+		}
+		// Only the labels ignore case; the code keeps the pattern's own case rules.
+		cc.productCode = regexp.MustCompile(`\b(?i:` + labelAlternation(cfg.ProductCodeLabels) + `)\.?\s*[:#\-]?\s*(` + cfg.ProductCodePattern + `)\b`)
+	}
+
+	if cfg.InvoiceNumberShape != "" {
+		re, err := regexp.Compile(cfg.InvoiceNumberShape)
+		if err != nil {
+			return nil, fmt.Errorf("invoice number shape: %w", err)
+		}
+		if re.NumSubexp() > 1 {
+			return nil, fmt.Errorf("invoice number shape must have at most one capture group")
+		}
+		if cfg.InvoiceNumberSearchLines < 1 {
+			return nil, fmt.Errorf("invoice number search lines %d must be positive", cfg.InvoiceNumberSearchLines)
+		}
+		cc.invoiceNumberShape = re
+	}
+
+	if cfg.MaxPages < 0 {
+		return nil, fmt.Errorf("max pages %d must not be negative", cfg.MaxPages)
+	}
+
+	if cfg.AmountPrecision < 0 || cfg.AmountPrecision > 6 {
+		return nil, fmt.Errorf("amount precision %d must be between 0 and 6", cfg.AmountPrecision)
+	}
+
+	switch cfg.DigitGrouping {
+	case GroupingAny, GroupingWestern, GroupingIndian:
+	default:
+		return nil, fmt.Errorf("digit grouping %q must be one of %s, %s or empty", cfg.DigitGrouping, GroupingWestern, GroupingIndian)
+	}
+
+	if err := validateEngines(cfg.Engines); err != nil {
+		return nil, err
+	}
+
+	if cfg.MinPopulatedFields < 0 {
+		return nil, fmt.Errorf("minimum populated fields %d must not be negative", cfg.MinPopulatedFields)
+	}
+
+	if cfg.OCRFallbackChars < 0 {
+		return nil, fmt.Errorf("OCR fallback chars %d must not be negative", cfg.OCRFallbackChars)
+	}
+
+	if cfg.PythonWorkers < 0 {
+		return nil, fmt.Errorf("python workers %d must not be negative", cfg.PythonWorkers)
+	}
+
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout %s must not be negative", cfg.Timeout)
+	}
+
+	if cfg.CacheSize < 0 {
+		return nil, fmt.Errorf("cache size %d must not be negative", cfg.CacheSize)
+	}
+
+	if cfg.MaxNotesSize < 0 {
+		return nil, fmt.Errorf("max notes size %d must not be negative", cfg.MaxNotesSize)
+	}
+
+	if cfg.MaxTextSize < 0 {
+		return nil, fmt.Errorf("max text size %d must not be negative", cfg.MaxTextSize)
+	}
+
+	if cfg.FiscalYearStartMonth < time.January || cfg.FiscalYearStartMonth > time.December {
+		return nil, fmt.Errorf("fiscal year start month %d must be between 1 and 12", cfg.FiscalYearStartMonth)
+	}
+
+	fieldRules, err := compileFieldRules(cfg.FieldRules)
+	if err != nil {
+		return nil, err
+	}
+	cc.fieldRules = fieldRules
+
+	customFields, err := compileCustomFields(cfg.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	cc.customFields = customFields
+
+	if err := validateIDRules(cfg.IDRules); err != nil {
+		return nil, err
+	}
+
+	switch cfg.DateOrder {
+	case "", DateOrderDayFirst, DateOrderMonthFirst, DateOrderAuto:
+	default:
+		return nil, fmt.Errorf("date order %q must be one of %s, %s or %s", cfg.DateOrder, DateOrderDayFirst, DateOrderMonthFirst, DateOrderAuto)
+	}
+
+	if cfg.DateLayout != "" {
+		if err := validateDateLayout(cfg.DateLayout); err != nil {
+			return nil, err
+		}
+	}
+
+	return cc, nil
+}
+
+// activeConfig returns the configuration in effect.
+func activeConfig() *compiledConfig {
+	return current.Load()
+}
+
+// gstLabelPattern compiles the GST labels into one pattern capturing the value
+// that follows any of them.
+func gstLabelPattern(labels []string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b(?:` + labelAlternation(labels) + `)\.?\s*[:\-]?\s*(\S+)`)
+}
+
+// labelAlternation turns labels into a regular expression alternation in which
+// words may be separated by any whitespace. Longer labels are tried first so
+// that "GSTIN No" is not cut short at "GSTIN".
+func labelAlternation(labels []string) string {
+	alternatives := make([]string, len(labels))
+	for i, label := range labels {
+		words := strings.Fields(label)
+		for j, word := range words {
+			words[j] = regexp.QuoteMeta(word)
+		}
+		alternatives[i] = strings.Join(words, `\s+`)
+	}
+	slices.SortStableFunc(alternatives, func(a, b string) int { return len(b) - len(a) })
+	return strings.Join(alternatives, "|")
+}
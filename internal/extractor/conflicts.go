@@ -0,0 +1,77 @@
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// fieldValidators check the format of fields whose shape is known. They decide
+// which value to keep when the two text layouts disagree.
+var fieldValidators = map[string]func(string) bool{
+	"invoice_date": validDate,
+	"order_date":   validDate,
+	"state_code":   validStateCode,
+}
+
+func validDate(s string) bool {
+	_, ok := parseDate(s)
+	return ok
+}
+
+// validStateCode reports whether s is one of the two-digit GST state codes:
+// 01-38 for states and union territories, 97 for other territory, 99 for
+// the centre jurisdiction.
+func validStateCode(s string) bool {
+	n, err := strconv.Atoi(s)
+	if err != nil || len(s) != 2 {
+		return false
+	}
+	return (n >= 1 && n <= 38) || n == 97 || n == 99
+}
+
+// matchAcrossModes extracts field from the primary layout like match does, then
+// cross-checks it against what the same pattern finds in the secondary layout.
+// When both found a value and they disagree, the primary value is kept unless
+// only the secondary one passes the field's format check, and the conflict is
+// recorded as a warning with both values.
+//
+// Conflicts, differing matches within the primary layout and a kept value that
+// fails its format check are also recorded as ambiguities for strict mode.
+func (d *InvoiceDetails) matchAcrossModes(field string, dst *string, re *regexp.Regexp, primary, secondary string) {
+	d.match(field, dst, re, primary)
+	if *dst == "" {
+		return
+	}
+	valid := fieldValidators[field]
+	defer func() {
+		if valid != nil && !valid(*dst) {
+			d.ambiguous("%s %q is not in the expected format", field, *dst)
+		}
+	}()
+
+	for _, m := range re.FindAllStringSubmatch(primary, -1) {
+		if value := cleanMatch(m, 1); value != "" && value != *dst {
+			d.ambiguous("%s matched both %q and %q in simple layout", field, *dst, value)
+			d.doubt(field, doubtMultipleMatches)
+			break
+		}
+	}
+
+	other := findStringSubmatchAndClean(re, secondary, 1)
+	if other == "" {
+		return
+	}
+	if *dst == other {
+		d.confirm(field)
+		return
+	}
+
+	kept := *dst
+	if valid != nil && !valid(kept) && valid(other) {
+		kept = other
+	}
+	d.warn(SeverityWarning, WarnLayoutConflict, "conflicting %s: %q in simple layout, %q in column layout; kept %q", field, *dst, other, kept)
+	d.ambiguous("%s is %q in simple layout but %q in column layout", field, *dst, other)
+	d.doubt(field, doubtLayoutConflict)
+	*dst = kept
+}
@@ -0,0 +1,20 @@
+package extractor
+
+// parseBillingContacts reads the buyer's phone and email from the billing block
+// alone, so they are never confused with the seller's contact details. A phone
+// that cannot be normalized is left out with a warning.
+func (d *InvoiceDetails) parseBillingContacts(block string) {
+	var phone string
+	d.match("billing_phone", &phone, rePhone, block)
+	if phone != "" {
+		if _, ok := normalizeE164(phone, activeConfig().DefaultCountryCode); ok {
+			d.BillingPhone = phone
+		} else {
+			d.warn(SeverityError, WarnInvalidFormat, "billing phone %q is not a valid phone number", phone)
+			delete(d.MatchedBy, "billing_phone")
+		}
+	}
+
+	d.BillingEmail = reEmail.FindString(block)
+	d.recordMatch("billing_email", reEmail, d.BillingEmail)
+}
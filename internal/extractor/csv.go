@@ -0,0 +1,64 @@
+package extractor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WriteCSV writes d as a CSV header line of JSON field names followed by one
+// record, for tooling that ingests CSV. Every field of InvoiceDetails gets a
+// column, in declaration order, so the header is the same for every invoice.
+// Nested values such as line_items are written as JSON text; empty ones and
+// nil pointers as empty cells.
+func (d *InvoiceDetails) WriteCSV(w io.Writer) error {
+	var header, record []string
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		cell, err := csvCell(v.Field(i))
+		if err != nil {
+			return err
+		}
+		header = append(header, name)
+		record = append(record, cell)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.Write(record)
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell renders one field value as CSV cell text.
+func csvCell(f reflect.Value) (string, error) {
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'f', -1, 64), nil
+	case reflect.Pointer:
+		if f.IsNil() {
+			return "", nil
+		}
+		return csvCell(f.Elem())
+	case reflect.Slice, reflect.Map:
+		if f.Len() == 0 {
+			return "", nil
+		}
+	}
+	data, err := json.Marshal(f.Interface())
+	return string(data), err
+}
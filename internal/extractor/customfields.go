@@ -0,0 +1,76 @@
+package extractor
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+)
+
+// Limits on custom fields, which may come from untrusted requests.
+const (
+	// MaxCustomFields is the most custom fields one set may define.
+	MaxCustomFields = 20
+	// maxCustomPatternLen caps the length of each custom field's pattern.
+	maxCustomPatternLen = 512
+)
+
+// reCustomFieldName is the shape of a custom field name, as used for the key
+// in InvoiceDetails.CustomFields.
+var reCustomFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidateCustomFields checks a set of custom fields, as accepted by
+// Config.CustomFields and Options.CustomFields: at most MaxCustomFields
+// names in lower snake case, each mapped to a valid regular expression.
+func ValidateCustomFields(fields map[string]string) error {
+	_, err := compileCustomFields(fields)
+	return err
+}
+
+// compileCustomFields validates and compiles a set of custom fields.
+func compileCustomFields(fields map[string]string) (map[string]*regexp.Regexp, error) {
+	if len(fields) > MaxCustomFields {
+		return nil, fmt.Errorf("%d custom fields given, at most %d are allowed", len(fields), MaxCustomFields)
+	}
+	compiled := make(map[string]*regexp.Regexp, len(fields))
+	for name, pattern := range fields {
+		if !reCustomFieldName.MatchString(name) {
+			return nil, fmt.Errorf("custom field name %q must be lower case letters, digits and underscores, starting with a letter, at most 64 characters", name)
+		}
+		if len(pattern) > maxCustomPatternLen {
+			return nil, fmt.Errorf("custom field %s: pattern is longer than %d bytes", name, maxCustomPatternLen)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %s: %w", name, err)
+		}
+		compiled[name] = re
+	}
+	return compiled, nil
+}
+
+// parseCustomFields reads the configured custom fields, and those of the
+// request, which take precedence on a shared name, into CustomFields. Each
+// is matched against the simple layout, then the column layout; the value is
+// the pattern's first capture group, or the whole match when it has none.
+// Fields that match nothing are reported empty.
+func (d *InvoiceDetails) parseCustomFields(request map[string]*regexp.Regexp, simpleText, columnText string) {
+	fields := maps.Clone(activeConfig().customFields)
+	if fields == nil {
+		fields = make(map[string]*regexp.Regexp, len(request))
+	}
+	maps.Copy(fields, request)
+	if len(fields) == 0 {
+		return
+	}
+
+	group := func(re *regexp.Regexp) int { return min(re.NumSubexp(), 1) }
+	d.CustomFields = make(map[string]string, len(fields))
+	for name, re := range fields {
+		value := findStringSubmatchAndClean(re, simpleText, group(re))
+		if value == "" {
+			value = findStringSubmatchAndClean(re, columnText, group(re))
+		}
+		d.CustomFields[name] = value
+		d.recordMatch("custom_fields."+name, re, value)
+	}
+}
@@ -0,0 +1,101 @@
+package extractor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// reNumericDate splits a numeric date such as "02.01.2006" into its parts.
+var reNumericDate = regexp.MustCompile(`^(\d{1,2})[./-](\d{1,2})[./-](\d{4})$`)
+
+// Orders in which the day and month of numeric dates are read, see
+// Config.DateOrder.
+const (
+	DateOrderDayFirst   = "dmy"
+	DateOrderMonthFirst = "mdy"
+	// DateOrderAuto reads the order from the date itself: a date is taken as
+	// day-first or month-first only when one of its parts exceeds 12, or both
+	// are equal. Dates such as 03/04/2024 are rejected as ambiguous.
+	DateOrderAuto = "auto"
+)
+
+// parseDate parses a numeric date as printed on the invoice, reading its day
+// and month in the configured Config.DateOrder. The second return value is
+// false when raw is not a valid calendar date in that order, or, for
+// DateOrderAuto, when the order cannot be told.
+func parseDate(raw string) (time.Time, bool) {
+	parts := reNumericDate.FindStringSubmatch(raw)
+	if parts == nil {
+		return time.Time{}, false
+	}
+	first, _ := strconv.Atoi(parts[1])
+	second, _ := strconv.Atoi(parts[2])
+
+	day, month := parts[1], parts[2]
+	switch activeConfig().DateOrder {
+	case DateOrderMonthFirst:
+		day, month = month, day
+	case DateOrderAuto:
+		switch {
+		case first > 12 || first == second:
+		case second > 12:
+			day, month = month, day
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	t, err := time.Parse("2/1/2006", day+"/"+month+"/"+parts[3])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// formatDate re-renders a printed date in layout, or returns "" when the date
+// cannot be parsed.
+func formatDate(raw, layout string) string {
+	t, ok := parseDate(raw)
+	if !ok {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// isoDate renders a printed date as YYYY-MM-DD, or returns "" when the date
+// cannot be parsed.
+func isoDate(raw string) string {
+	return formatDate(raw, time.DateOnly)
+}
+
+// validateDateLayout checks that layout is a Go time layout that renders the
+// year, month and day, by formatting a sample date and parsing it back.
+func validateDateLayout(layout string) error {
+	sample := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, sample.Format(layout))
+	if err != nil || !parsed.Equal(sample) {
+		return fmt.Errorf("date layout %q must render the year, month and day (e.g. %q)", layout, "02 Jan 2006")
+	}
+	return nil
+}
+
+// fiscalPeriod returns the fiscal year and quarter t falls in, for a fiscal year
+// starting on the first day of startMonth. A year starting in January is labelled
+// by its calendar year ("2024"); any other by the two years it spans ("2024-25").
+// With an April start, 31 March 2024 is in 2023-24 Q4 and 1 April 2024 in 2024-25 Q1.
+func fiscalPeriod(t time.Time, startMonth time.Month) (year, quarter string) {
+	startYear := t.Year()
+	if t.Month() < startMonth {
+		startYear--
+	}
+	if startMonth == time.January {
+		year = strconv.Itoa(startYear)
+	} else {
+		year = fmt.Sprintf("%d-%02d", startYear, (startYear+1)%100)
+	}
+
+	monthsIn := (int(t.Month()) - int(startMonth) + 12) % 12
+	return year, "Q" + strconv.Itoa(monthsIn/3+1)
+}
@@ -0,0 +1,57 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Engines are the Python libraries the text extraction script can read a PDF's
+// text layer with, selected by its --engine flag. Only the default engine
+// supports the 'columns' and 'ocr' modes.
+const (
+	EnginePDFPlumber = "pdfplumber"
+	EnginePDFMiner   = "pdfminer"
+	EnginePDFium     = "pdfium"
+
+	defaultEngine = EnginePDFPlumber
+)
+
+// minUsableChars is the number of letters and digits below which a text layer
+// is considered empty, as with scanned pages or broken font encodings.
+const minUsableChars = 20
+
+// validateEngines checks that engines is a non-empty list of known engines.
+func validateEngines(engines []string) error {
+	if len(engines) == 0 {
+		return fmt.Errorf("at least one extraction engine is required")
+	}
+	for _, engine := range engines {
+		switch engine {
+		case EnginePDFPlumber, EnginePDFMiner, EnginePDFium:
+		default:
+			return fmt.Errorf("unknown extraction engine %q", engine)
+		}
+	}
+	return nil
+}
+
+// usableText reports whether an engine's text is worth parsing: it carries a
+// reasonable amount of letters and digits, and is not dominated by glyphs the
+// engine could not map, which pdfminer prints as "(cid:NN)" and others as U+FFFD.
+func usableText(text string) bool {
+	n := alnumCount(text)
+	unmapped := strings.Count(text, "(cid:") + strings.Count(text, "\uFFFD")
+	return n >= minUsableChars && unmapped*10 < n
+}
+
+// alnumCount counts the letters and digits in text.
+func alnumCount(text string) int {
+	n := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
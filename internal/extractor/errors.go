@@ -0,0 +1,73 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Errors an extraction can fail with, to be matched with errors.Is. They are
+// wrapped with the details of the failure.
+var (
+	// ErrInvalidPDF is returned when the PDF cannot be read, being corrupt,
+	// truncated or encrypted.
+	ErrInvalidPDF = errors.New("invalid PDF")
+
+	// ErrNotPDF is returned for input that does not start with the PDF
+	// header. It is an ErrInvalidPDF.
+	ErrNotPDF = fmt.Errorf("%w: the file is not a PDF", ErrInvalidPDF)
+
+	// ErrTooManyPages is returned, before any text is extracted, for
+	// documents with more pages than Config.MaxPages.
+	ErrTooManyPages = errors.New("too many pages")
+
+	// ErrPythonUnavailable is returned when the Python interpreter, the text
+	// extraction script or the libraries it needs cannot be found.
+	ErrPythonUnavailable = errors.New("python text extraction unavailable")
+
+	// ErrExtractionTimeout is returned when an extraction is cut short by its
+	// deadline, be it Config.Timeout or one set on the caller's context. The
+	// Python processes still running at that point are killed.
+	ErrExtractionTimeout = errors.New("extraction timed out")
+)
+
+// invalidPDFMarkers are the exception names the PDF libraries raise for files
+// they cannot parse.
+var invalidPDFMarkers = []string{
+	"PdfminerException",
+	"PDFSyntaxError",
+	"PSEOF",
+	"PDFPasswordIncorrect",
+	"PdfiumError",
+}
+
+// missingLibraryMarkers are the exceptions Python raises when a library the
+// script imports is not installed.
+var missingLibraryMarkers = []string{
+	"ModuleNotFoundError",
+	"ImportError",
+}
+
+// classifyScriptError wraps err, a failure of the text extraction script whose
+// error output was output, with ErrPythonUnavailable or ErrInvalidPDF when it
+// is recognisably one of them. Other errors are returned as they are.
+func classifyScriptError(err error, output string) error {
+	switch {
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist), containsAny(output, missingLibraryMarkers):
+		return fmt.Errorf("%w: %w", ErrPythonUnavailable, err)
+	case containsAny(output, invalidPDFMarkers):
+		return fmt.Errorf("%w: %w", ErrInvalidPDF, err)
+	}
+	return err
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// reExchangeRate matches a printed conversion rate, such as
+	// "Exchange Rate: 83.25" or "Conversion Rate 1 USD = INR 83.25".
+	reExchangeRate = regexp.MustCompile(`(?i)\b(?:Exchange|Conversion)\s+Rate\b\s*[:\-]?\s*(?:1\s*[A-Z]{3}\s*=\s*(?:[A-Z]{3}|Rs\.?|₹)?\s*)?(\d+(?:\.\d+)?)`)
+
+	// reCurrencyAmount matches an ISO currency code followed by an amount.
+	reCurrencyAmount = regexp.MustCompile(`\b(` + strings.Join(currencyCodes, "|") + `)\s*(\(?-?[\d,]*\d\.\d{2,}\)?)`)
+)
+
+// exchangeRateTolerance is the relative difference allowed between the primary
+// total and the converted foreign total, since rates are printed rounded.
+const exchangeRateTolerance = 0.01
+
+// parseForeignTotal extracts the exchange rate and, from the total lines, a total
+// stated in a currency other than the primary one. When the rate and both totals
+// are known it warns if converting one total does not give the other.
+// The primary total is never changed.
+func (d *InvoiceDetails) parseForeignTotal(text string) {
+	if m := reExchangeRate.FindStringSubmatch(text); m != nil {
+		if rate, err := strconv.ParseFloat(m[1], 64); err == nil && rate > 0 {
+			d.ExchangeRate = rate
+			d.recordMatch("exchange_rate", reExchangeRate, m[1])
+		}
+	}
+
+	var pairs [][]string
+	for _, label := range activeConfig().totalLabels {
+		for _, line := range label.FindAllString(text, -1) {
+			pairs = append(pairs, reCurrencyAmount.FindAllStringSubmatch(line, -1)...)
+		}
+	}
+
+	// A line stating both totals starts with either; the currency printed
+	// against the primary total itself is the primary currency.
+	for _, m := range pairs {
+		if d.TotalAmount != "" && strings.TrimSpace(m[2]) == d.TotalAmount {
+			d.Currency = m[1]
+			break
+		}
+	}
+	primary := d.Currency
+	if primary == "" {
+		primary = "INR"
+	}
+	for _, m := range pairs {
+		if m[1] == primary || d.ForeignCurrency != "" {
+			continue
+		}
+		if value, ok := parseAmount(m[2]); ok {
+			d.ForeignCurrency = m[1]
+			d.TotalAmountForeign = math.Abs(value)
+			d.recordMatch("total_amount_foreign", reCurrencyAmount, m[0])
+		}
+	}
+
+	total := math.Abs(d.TotalAmountValue)
+	if d.ExchangeRate == 0 || d.TotalAmountForeign == 0 || total == 0 {
+		return
+	}
+	// The rate may be quoted either way round; accept whichever direction fits.
+	near := func(got, want float64) bool { return math.Abs(got-want) <= want*exchangeRateTolerance }
+	if !near(d.TotalAmountForeign*d.ExchangeRate, total) && !near(total*d.ExchangeRate, d.TotalAmountForeign) {
+		d.warn(SeverityError, WarnExchangeRateMismatch, "foreign total %s %.2f at rate %g does not match the total %.2f",
+			d.ForeignCurrency, d.TotalAmountForeign, d.ExchangeRate, total)
+	}
+}
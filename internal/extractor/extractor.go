@@ -5,15 +5,21 @@ package extractor
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
-
-	"encoding/json"  // Debug
+	"time"
 )
 
 // InvoiceDetails holds the structured data extracted from the PDF.
@@ -31,10 +37,244 @@ type InvoiceDetails struct {
 	TotalAmount    string `json:"total_amount"`
 	HSN            string `json:"hsn"`
 	ASN            string `json:"asn"` // A unique product or item code.
+
+	// BillingAddressLines holds the billing address as printed, one line per
+	// element, when Config.AddressLines is set. BillingAddress always carries
+	// the same lines joined with ", ".
+	BillingAddressLines []string `json:"billing_address_lines,omitempty"`
+
+	// BillingPhone and BillingEmail are the buyer's contact details, read from
+	// the billing block only. BillingPhone is set only when it is a valid number.
+	BillingPhone string `json:"billing_phone"`
+	BillingEmail string `json:"billing_email"`
+
+	// BillingNameType guesses whether BillingName is a company or an individual
+	// (see NameCompany and NameIndividual); it is empty when unclear.
+	BillingNameType string `json:"billing_name_type"`
+
+	// InvoiceDateISO and OrderDateISO are the dates as YYYY-MM-DD, read in the
+	// configured Config.DateOrder. They are empty when a date cannot be parsed,
+	// or its day and month cannot be told apart.
+	InvoiceDateISO string `json:"invoice_date_iso"`
+	OrderDateISO   string `json:"order_date_iso"`
+
+	// InvoiceDateFormatted and OrderDateFormatted render the dates in the
+	// configured Config.DateLayout. They are empty when no layout is configured.
+	InvoiceDateFormatted string `json:"invoice_date_formatted,omitempty"`
+	OrderDateFormatted   string `json:"order_date_formatted,omitempty"`
+
+	// FiscalYear ("2024-25") and FiscalQuarter ("Q1") bucket the invoice date
+	// by the configured Config.FiscalYearStartMonth.
+	FiscalYear    string `json:"fiscal_year"`
+	FiscalQuarter string `json:"fiscal_quarter"`
+
+	// ChallanNumber identifies the delivery challan (or delivery note) the goods
+	// shipped under; ReferenceNumber is any other printed reference number.
+	ChallanNumber   string `json:"challan_number"`
+	ReferenceNumber string `json:"reference_number"`
+
+	// NormalizedIDs holds the ID fields rewritten by the configured Config.IDRules,
+	// keyed by field name. The raw values above are left as printed.
+	NormalizedIDs map[string]string `json:"normalized_ids,omitempty"`
+
+	// DocumentType distinguishes regular invoices from credit and debit notes.
+	DocumentType string `json:"document_type"`
+	// TaxAmountValue and TotalAmountValue are the numeric forms of TaxAmount and
+	// TotalAmount. They are negative for credits, whether the document printed
+	// a minus sign, parentheses, or is a credit note.
+	TaxAmountValue   float64 `json:"tax_amount_value"`
+	TotalAmountValue float64 `json:"total_amount_value"`
+	// CGST, SGST and IGST are the GST components as printed on their own
+	// lines; SGST also carries UTGST. They are empty when the invoice prints
+	// only the combined TaxAmount. IsInterState is set when IGST is charged
+	// without CGST and SGST, as on a supply between states.
+	CGST         string `json:"cgst"`
+	SGST         string `json:"sgst"`
+	IGST         string `json:"igst"`
+	IsInterState bool   `json:"is_inter_state"`
+	// Currency is the ISO 4217 code of the amounts, when the document states one.
+	Currency string `json:"currency"`
+	// AmountPaid and BalanceDue track partial payment. Either is derived from
+	// the total when only the other is printed; both are null when neither is.
+	AmountPaid *float64 `json:"amount_paid"`
+	BalanceDue *float64 `json:"balance_due"`
+	// Subtotal, Discount and RoundOff are the breakdown printed above the
+	// total, which Validate checks adds up to it. RoundOff is signed; all three
+	// are null when not printed.
+	Subtotal *float64 `json:"subtotal"`
+	Discount *float64 `json:"discount"`
+	RoundOff *float64 `json:"round_off"`
+	// Export invoices may also state the total in a second currency, and the
+	// rate used to convert between the two.
+	ExchangeRate       float64 `json:"exchange_rate"`
+	TotalAmountForeign float64 `json:"total_amount_foreign"`
+	ForeignCurrency    string  `json:"foreign_currency"`
+
+	// LineItems lists the rows of the item table, read from the simple layout or,
+	// failing that, the column layout. It is empty, not an error, when the table
+	// could not be recognised.
+	LineItems []LineItem `json:"line_items"`
+	// TotalQuantity and ItemCount are the document-level counts printed below
+	// the item table, zero when the document states none.
+	TotalQuantity float64 `json:"total_quantity"`
+	ItemCount     int     `json:"item_count"`
+
+	// HSNSummary holds the rows of the HSN-wise summary table, when the
+	// invoice prints one.
+	HSNSummary []HSNSummaryRow `json:"hsn_summary"`
+
+	// GSTINs lists every GSTIN in the document, labelled by party.
+	GSTINs []PartyGSTIN `json:"gstins"`
+
+	// Notes holds the free text printed under "Notes", "Remarks" or "Terms"
+	// headings, one line per section.
+	Notes string `json:"notes"`
+
+	// PlaceOfSupply is the state the invoice declares as its place of supply.
+	PlaceOfSupply *PlaceOfSupply `json:"place_of_supply,omitempty"`
+
+	// Signed reports whether the document carries an authorised signatory line or a
+	// digital signature marker. It is a textual signal, not signature verification.
+	Signed bool `json:"signed"`
+
+	// CompositionScheme reports that the seller is registered under the GST
+	// composition scheme, so the invoice is not expected to charge tax.
+	CompositionScheme bool `json:"composition_scheme"`
+
+	// Seller contact details. ContactPhone is kept as printed; ContactPhoneE164
+	// is only set when the number could be normalized unambiguously.
+	ContactPhone     string `json:"contact_phone"`
+	ContactPhoneE164 string `json:"contact_phone_e164"`
+	ContactEmail     string `json:"contact_email"`
+
+	// CIN is the seller's Corporate Identification Number, usually printed in
+	// the footer. It is only set when its structure is valid.
+	CIN string `json:"cin"`
+
+	// UPIID is the seller's UPI handle ("name@bank") and UPIPaymentString the
+	// "upi://pay?..." link printed or encoded for payment, when present.
+	UPIID            string `json:"upi_id"`
+	UPIPaymentString string `json:"upi_payment_string"`
+
+	// Source names the engine that produced the text the header fields were
+	// parsed from, or "ocr" when it was read through OCR; parts read by
+	// different engines list each, comma-separated.
+	Source string `json:"source"`
+
+	// ExtractorVersion identifies the extractor build that produced the result
+	// and ConfigVersion fingerprints the configuration it ran with, so results
+	// from an older build or different settings can be found and reprocessed.
+	// Together with Source they record which engine produced the result.
+	ExtractorVersion string `json:"extractor_version"`
+	ConfigVersion    string `json:"config_version"`
+
+	// SourceSize and SourceSHA256 identify the exact PDF bytes that were processed,
+	// tying the result to its file. For an invoice uploaded in parts they cover
+	// the parts concatenated in order.
+	SourceSize   int64  `json:"source_size"`
+	SourceSHA256 string `json:"source_sha256"`
+
+	// Extracted reports whether at least Config.MinPopulatedFields fields were
+	// read from the document. An image-only PDF without OCR yields false.
+	Extracted bool `json:"extracted"`
+
+	// CustomFields holds the values read for the custom fields of
+	// Config.CustomFields and Options.CustomFields, by name; a field that
+	// matched nothing is empty. It is absent when no custom fields are set.
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+
+	// MissingFields names, by JSON name, the fields read from the document
+	// that came back empty, so results needing manual review can be spotted.
+	MissingFields []string `json:"missing_fields"`
+
+	// Partial is set when some text passes were abandoned at the soft deadline,
+	// leaving the fields they would have produced empty.
+	Partial bool `json:"partial,omitempty"`
+
+	// Warnings lists non-fatal problems noticed while parsing, each with a
+	// severity so clients can tell what needs review.
+	Warnings []Warning `json:"warnings,omitempty"`
+
+	// Confidence scores each field read from the document between 0 and 1, by
+	// JSON name. A labelled value that both text layouts agree on and that
+	// passes its format check scores 1; values read from one layout only,
+	// inferred, contested or malformed score lower.
+	Confidence map[string]float64 `json:"confidence"`
+
+	// HeuristicFields names the fields whose value was inferred from its shape
+	// or position rather than read from a label, and so deserves less trust.
+	HeuristicFields []string `json:"heuristic_fields,omitempty"`
+
+	// Validated is set when the result was checked by Validate, as enabled by
+	// Config.Validate; ValidationFailures lists what the checks found.
+	Validated          bool                `json:"validated,omitempty"`
+	ValidationFailures []ValidationFailure `json:"validation_failures,omitempty"`
+
+	// MatchedBy maps each populated field to the pattern that produced it.
+	// It is only filled in when requested through Options.MatchedBy.
+	MatchedBy map[string]string `json:"_matched_by,omitempty"`
+
+	// RawText is the text the patterns ran against. It is only filled in when
+	// requested through Options.RawText.
+	RawText *RawText `json:"_raw_text,omitempty"`
+
+	// ambiguities lists the guesses made while parsing, which fail the
+	// extraction in strict mode.
+	ambiguities []string
+
+	// mismatches are the reconciliation failures found while parsing, see
+	// mismatch.
+	mismatches []mismatch
+
+	// doubts and confirmed are the signals Confidence is scored from, see
+	// scoreConfidence.
+	doubts    map[string]float64
+	confirmed map[string]bool
 }
 
-// sellerGSTIN is the GST number of the seller, used to avoid misattributing it to the client.
-const sellerGSTIN = "19APGPS1824K1ZI"
+// ambiguous records that a value had to be guessed, either among several
+// candidates or despite failing its format check. See Options.Strict.
+func (d *InvoiceDetails) ambiguous(format string, args ...any) {
+	d.ambiguities = append(d.ambiguities, fmt.Sprintf(format, args...))
+}
+
+// AmbiguityError is returned in strict mode when a field could not be extracted
+// unambiguously. Problems describes each ambiguity found.
+type AmbiguityError struct {
+	Problems []string
+}
+
+func (e *AmbiguityError) Error() string {
+	return fmt.Sprintf("ambiguous extraction: %s", strings.Join(e.Problems, "; "))
+}
+
+// match applies re to text and stores the cleaned first capture group in *dst.
+func (d *InvoiceDetails) match(field string, dst *string, re *regexp.Regexp, text string) {
+	*dst = findStringSubmatchAndClean(re, text, 1)
+	d.recordMatch(field, re, *dst)
+}
+
+// recordMatch notes re as the pattern that produced value for field,
+// provided provenance is being tracked and a value was actually found.
+func (d *InvoiceDetails) recordMatch(field string, re *regexp.Regexp, value string) {
+	if d.MatchedBy != nil && value != "" {
+		d.MatchedBy[field] = re.String()
+	}
+}
+
+// Document types reported in InvoiceDetails.DocumentType.
+const (
+	DocumentTypeInvoice    = "invoice"
+	DocumentTypeCreditNote = "credit_note"
+	DocumentTypeDebitNote  = "debit_note"
+)
+
+// Default paths of the Python interpreter and the text extraction script,
+// relative to the working directory of the server. See Config.PythonPath.
+const (
+	DefaultPythonPath = "./tools/venv/bin/python3"
+	DefaultScriptPath = "tools/pdf_text_extractor.py"
+)
 
 // pre-compiled regular expressions for efficient matching.
 var (
@@ -43,74 +283,402 @@ var (
 	reOrderNo      = regexp.MustCompile(`(?i)Order\s*Number\s*[:\-]?\s*([A-Z0-9\-]+)`)
 	reOrderDate    = regexp.MustCompile(`(?i)Order\s*Date\s*[:\-]?\s*([0-9]{2}[./-][0-9]{2}[./-][0-9]{4})`)
 	reStateCode    = regexp.MustCompile(`(?i)State/UT\s*Code\s*[:\-]?\s*(\d{2})`)
-	reGST          = regexp.MustCompile(`(?i)GST(?:IN)?(?: Registration)? No\s*[:\-]?\s*(\S+)`)
-	reTaxAndTotal  = regexp.MustCompile(`(?i)TOTAL\s*[:\-]?\s*.*?([\d,]+\.\d{2})\s*.*?([\d,]+\.\d{2})`)
 	reHSN          = regexp.MustCompile(`(?i)HSN\s*[:\-]?\s*(\d+)`)
-	reASN          = regexp.MustCompile(`[\|\s]+([A-Z0-9]{10})[\s]*(\(|₹)`)
+	reASN          = regexp.MustCompile(`[\|\s]+([A-Z0-9]{10})[\s]*(\(|\x{20B9})`) // \x{20B9} is the rupee sign.
 	reBillingBlock = regexp.MustCompile(`(?is)Billing Address\s*:\s*(.*?)\s*(?:Shipping Address|Invoice Number|State/UT Code)`)
+	rePhone        = regexp.MustCompile(`(?i)(?:Phone|Tel|Mobile|Mob|Contact)(?:\s*No)?\.?\s*[:\-]?\s*(\+?\d[\d\s\-()]{6,}\d)`)
+	reEmail        = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	reChallan      = regexp.MustCompile(`(?i)\bChallan\s*(?:No\.?|Number)\s*[:\-]?\s*([A-Z0-9/\-]*\d[A-Z0-9/\-]*)`)
+	reDeliveryNote = regexp.MustCompile(`(?i)\bDelivery\s+Note\s*(?:No\.?|Number)?\s*[:\-]?\s*([A-Z0-9/\-]*\d[A-Z0-9/\-]*)`)
+	reReferenceNo  = regexp.MustCompile(`(?i)\bRef(?:erence)?\.?\s*(?:No\.?|Number)\s*[:\-]?\s*([A-Z0-9/\-]*\d[A-Z0-9/\-]*)`)
+	reSignatory    = regexp.MustCompile(`(?i)\bAuthori[sz]ed\s+Signatory\b|\bDigitally\s+signed\s+by\b`)
+	reComposition  = regexp.MustCompile(`(?i)\bcomposition\s+(?:taxable\s+person|dealer|scheme)\b`)
+	reCreditNote   = regexp.MustCompile(`(?i)\bCredit\s+Note\b`)
+	reDebitNote    = regexp.MustCompile(`(?i)\bDebit\s+Note\b`)
 )
 
+// Options tunes a single extraction. The zero value runs the default text extraction.
+type Options struct {
+	// OCRPages lists 1-based page numbers to additionally run through OCR.
+	// The OCR'd text is merged with the extracted text before parsing, which
+	// recovers values printed inside images such as a scanned header or logo.
+	OCRPages []int
+
+	// MatchedBy records, for every populated field, the regular expression that
+	// produced its value. It is an audit aid and is off by default.
+	MatchedBy bool
+
+	// SoftTimeout, when positive, runs the text passes concurrently and parses
+	// whatever they produced once it elapses, marking the result as partial.
+	// Passes still running at that point are cancelled.
+	SoftTimeout time.Duration
+
+	// Timeout, when positive, replaces Config.Timeout as the limit on this
+	// extraction, e.g. to give a background job longer than a request that is
+	// waiting for its answer.
+	Timeout time.Duration
+
+	// TextSource selects where the text is read from: TextSourceAuto (the
+	// default, also when empty), TextSourceText or TextSourceOCR.
+	TextSource string
+
+	// Strict fails the extraction with an *AmbiguityError, instead of returning
+	// a best guess, when a field matched conflicting values or a value failed
+	// its format check.
+	Strict bool
+
+	// CustomFields adds custom fields to those of Config.CustomFields for this
+	// extraction, replacing any of the same name. See ValidateCustomFields.
+	CustomFields map[string]string
+
+	// RawText includes the text of both layouts in the result, as it was
+	// parsed, to tell a bad text extraction from a bad match. The text is the
+	// whole document, so it is off by default.
+	RawText bool
+}
+
+// RawText is the text of the two layouts, after OCR merging, truncation and
+// numeral normalization.
+type RawText struct {
+	Simple  string `json:"simple"`
+	Columns string `json:"columns"`
+}
+
 // ExtractDetails is the primary function of the package. It takes a reader for a PDF file,
 // orchestrates the text extraction via a Python script, and then parses the text
 // to populate an InvoiceDetails struct.
 func ExtractDetails(file io.Reader) (*InvoiceDetails, error) {
-	// Buffer the reader content to allow it to be read multiple times.
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, file); err != nil {
-		return nil, fmt.Errorf("failed to buffer pdf content: %w", err)
-	}
+	return ExtractDetailsWithOptions(file, Options{})
+}
 
-	// Extract text using the Python script in two different layout modes.
-	simpleText, err := extractTextWithPython(bytes.NewReader(buf.Bytes()), "simple")
-	if err != nil {
+// ExtractDetailsWithOptions behaves like ExtractDetails but applies the given options.
+func ExtractDetailsWithOptions(file io.Reader, opts Options) (*InvoiceDetails, error) {
+	return ExtractDetailsContext(context.Background(), file, opts)
+}
+
+// ExtractDetailsContext behaves like ExtractDetailsWithOptions but stops when
+// ctx is done, killing the Python processes still running. An extraction cut
+// short by a deadline fails with ErrExtractionTimeout.
+func ExtractDetailsContext(ctx context.Context, file io.Reader, opts Options) (*InvoiceDetails, error) {
+	return ExtractDetailsFromPartsContext(ctx, []io.Reader{file}, opts)
+}
+
+// ExtractDetailsFromParts parses one invoice that was split across several PDF
+// files, such as a two-page invoice scanned as two documents. The text of each
+// part is extracted separately and concatenated in the given order before parsing.
+//
+// Results are cached by the content of the parts (see Config.CacheSize), so
+// a re-uploaded PDF is answered without running Python again. Cached results
+// are shared between callers and must not be modified.
+func ExtractDetailsFromParts(parts []io.Reader, opts Options) (*InvoiceDetails, error) {
+	return ExtractDetailsFromPartsContext(context.Background(), parts, opts)
+}
+
+// ExtractDetailsFromPartsContext behaves like ExtractDetailsFromParts but stops
+// when ctx is done, as ExtractDetailsContext does.
+func ExtractDetailsFromPartsContext(ctx context.Context, parts []io.Reader, opts Options) (*InvoiceDetails, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("no pdf parts to extract")
+	}
+	if !validTextSource(opts.TextSource) {
+		return nil, fmt.Errorf("unknown text source %q", opts.TextSource)
+	}
+	if err := ValidateCustomFields(opts.CustomFields); err != nil {
 		return nil, err
 	}
-	columnText, err := extractTextWithPython(bytes.NewReader(buf.Bytes()), "columns")
+
+	// Buffer the reader content to allow it to be read multiple times,
+	// hashing it on the way in.
+	pdfs := make([][]byte, len(parts))
+	digest := sha256.New()
+	var size int64
+	for i, part := range parts {
+		var buf bytes.Buffer
+		n, err := io.Copy(io.MultiWriter(&buf, digest), part)
+		if err != nil {
+			return nil, &StageError{Stage: StageRead, Err: fmt.Errorf("failed to buffer pdf content: %w", err)}
+		}
+		size += n
+		if !IsPDF(buf.Bytes()) {
+			return nil, &StageError{Stage: StageRead, Err: ErrNotPDF}
+		}
+		pdfs[i] = buf.Bytes()
+	}
+	if err := checkPageCount(pdfs); err != nil {
+		return nil, &StageError{Stage: StageRead, Err: err}
+	}
+	sum := hex.EncodeToString(digest.Sum(nil))
+
+	details, err := results.get(ctx, cacheKey(sum, opts), func() (*InvoiceDetails, error) {
+		return extractParts(ctx, pdfs, sum, size, opts)
+	})
 	if err != nil {
 		return nil, err
 	}
-		//  DEBUG: Print the raw extracted text
-	// fmt.Println("----- SIMPLE TEXT -----")
-	// fmt.Println(simpleText)
+	if opts.Strict && len(details.ambiguities) > 0 {
+		return nil, &AmbiguityError{Problems: details.ambiguities}
+	}
+	return details, nil
+}
+
+// extractParts runs the text passes over the buffered parts and parses the
+// result. sum and size describe the parts together.
+func extractParts(ctx context.Context, pdfs [][]byte, sum string, size int64, opts Options) (*InvoiceDetails, error) {
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// Extract text using the Python script in two different layout modes,
+	// plus OCR of any requested pages.
+	passes := passesFor(opts)
+	texts := make(map[string]string, len(passes))
+	var sources []string
+	incomplete := make(map[string]bool)
+	ocrParts := 0
+	for i, pdf := range pdfs {
+		var partTexts map[string]passText
+		var err error
+		if opts.SoftTimeout > 0 {
+			partTexts, err = runPassesWithin(ctx, pdf, passes, opts.SoftTimeout)
+		} else {
+			partTexts, err = runPasses(ctx, pdf, passes)
+		}
+		if err = timeoutError(ctx, err); err != nil {
+			if len(pdfs) > 1 {
+				return nil, fmt.Errorf("part %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+
+		for _, p := range passes {
+			if _, ok := partTexts[p.mode]; !ok {
+				incomplete[p.mode] = true
+			}
+		}
+		scanned, err := textFromOCR(ctx, pdf, partTexts, opts)
+		if err = timeoutError(ctx, err); err != nil {
+			if len(pdfs) > 1 {
+				return nil, fmt.Errorf("part %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		if scanned {
+			ocrParts++
+		}
+
+		for _, mode := range []string{"simple", "columns", "ocr"} {
+			pt, ok := partTexts[mode]
+			if !ok {
+				continue
+			}
+			if mode == "simple" && !slices.Contains(sources, pt.engine) {
+				sources = append(sources, pt.engine)
+			}
+			text := pt.text
+			if prev, ok := texts[mode]; ok {
+				text = prev + "\n" + text
+			}
+			texts[mode] = text
+		}
+	}
+	simpleText, columnText := texts["simple"], texts["columns"]
 
-	// fmt.Println("----- COLUMN TEXT -----")
-	// fmt.Println(columnText)
+	// Header fields are parsed from the 'simple' layout, so that is where the
+	// OCR'd text is merged.
+	if ocrText, ok := texts["ocr"]; ok {
+		simpleText += "\n" + ocrText
+	}
+
+	// Bound the parsing work on huge documents. The patterns are RE2 and run in
+	// linear time, but a multi-megabyte text still multiplies across every field.
+	maxText := activeConfig().MaxTextSize
+	var truncated []string
+	var cut bool
+	if simpleText, cut = truncateText(simpleText, maxText); cut {
+		truncated = append(truncated, "simple")
+	}
+	if columnText, cut = truncateText(columnText, maxText); cut {
+		truncated = append(truncated, "columns")
+	}
+
+	// Regional invoices may print amounts in non-Latin digits or group thousands
+	// with no-break spaces; the patterns below only understand ASCII.
+	simpleText = normalizeNumerals(simpleText)
+	columnText = normalizeNumerals(columnText)
 
-	details := &InvoiceDetails{}
+	details := &InvoiceDetails{
+		Source:           strings.Join(sources, ","),
+		SourceSize:       size,
+		SourceSHA256:     sum,
+		ExtractorVersion: buildVersion(),
+		ConfigVersion:    activeConfig().version,
+	}
+	if opts.MatchedBy {
+		details.MatchedBy = make(map[string]string)
+	}
+	if opts.RawText {
+		details.RawText = &RawText{Simple: simpleText, Columns: columnText}
+	}
+	for _, p := range passes {
+		if incomplete[p.mode] {
+			details.Partial = true
+			details.warn(SeverityWarning, WarnPartialResult, "partial result: %s extraction did not finish within %s", p.mode, opts.SoftTimeout)
+		}
+	}
+	if ocrParts > 0 && opts.TextSource != TextSourceOCR {
+		details.warn(SeverityInfo, WarnOCRFallback, "%d of %d parts had no usable text layer and were read through OCR", ocrParts, len(pdfs))
+	}
+	for _, mode := range truncated {
+		details.warn(SeverityWarning, WarnTextTruncated, "%s text exceeded %d bytes and was truncated; fields past that point were not parsed", mode, maxText)
+	}
 
 	// --- Parse simple, single-line fields from the 'simple' text layout ---
-	details.InvoiceNumber = findStringSubmatchAndClean(reInvoiceNumber, simpleText, 1)
-	details.InvoiceDate = findStringSubmatchAndClean(reInvoiceDate, simpleText, 1)
-	details.OrderNumber = findStringSubmatchAndClean(reOrderNo, simpleText, 1)
-	details.OrderDate = findStringSubmatchAndClean(reOrderDate, simpleText, 1)
-	details.StateCode = findStringSubmatchAndClean(reStateCode, simpleText, 1)
-	details.HSN = findStringSubmatchAndClean(reHSN, simpleText, 1)
-	details.ASN = findStringSubmatchAndClean(reASN, simpleText, 1)
+	// Each is cross-checked against the 'columns' layout to catch mis-extractions.
+	details.matchField("invoice_number", &details.InvoiceNumber, simpleText, columnText)
+	if cfg := activeConfig(); details.InvoiceNumber == "" && cfg.invoiceNumberShape != nil {
+		// Some layouts print the number in the header without a label.
+		if n := guessInvoiceNumber(simpleText, cfg.invoiceNumberShape, cfg.InvoiceNumberSearchLines); n != "" {
+			details.InvoiceNumber = n
+			details.recordMatch("invoice_number", cfg.invoiceNumberShape, n)
+			details.HeuristicFields = append(details.HeuristicFields, "invoice_number")
+			details.doubt("invoice_number", doubtHeuristic)
+			details.ambiguous("invoice_number %q was guessed from its shape, not read from a label", n)
+		}
+	}
+	details.matchField("invoice_date", &details.InvoiceDate, simpleText, columnText)
+	details.matchField("order_number", &details.OrderNumber, simpleText, columnText)
+	details.matchField("order_date", &details.OrderDate, simpleText, columnText)
+	details.matchField("state_code", &details.StateCode, simpleText, columnText)
+	details.matchField("hsn", &details.HSN, simpleText, columnText)
+	if re := activeConfig().productCode; re != nil {
+		details.matchAcrossModes("asn", &details.ASN, re, simpleText, columnText)
+	}
+	if details.ASN == "" {
+		// Unlabelled codes are recognised by the table layout of the original vendor.
+		details.matchAcrossModes("asn", &details.ASN, reASN, simpleText, columnText)
+	}
+
+	details.matchField("challan_number", &details.ChallanNumber, simpleText, columnText)
+	details.matchField("reference_number", &details.ReferenceNumber, simpleText, columnText)
+
+	if activeConfig().CombinedText {
+		// Layouts that split a label from its value across the two modes.
+		combined := combineTexts(simpleText, columnText)
+		details.fillFromCombined(combined, details.fieldRulePatterns())
+	}
+
+	details.normalizeIDs(activeConfig().IDRules)
 
-	// Extract Tax and Total amounts from the "TOTAL" line.
-	if match := reTaxAndTotal.FindStringSubmatch(simpleText); len(match) >= 3 {
-		details.TaxAmount = strings.TrimSpace(match[1])
-		details.TotalAmount = strings.TrimSpace(match[2])
+	details.InvoiceDateISO = isoDate(details.InvoiceDate)
+	details.OrderDateISO = isoDate(details.OrderDate)
+	if t, ok := parseDate(details.InvoiceDate); ok {
+		details.FiscalYear, details.FiscalQuarter = fiscalPeriod(t, activeConfig().FiscalYearStartMonth)
 	}
 
+	if layout := activeConfig().DateLayout; layout != "" {
+		details.InvoiceDateFormatted = formatDate(details.InvoiceDate, layout)
+		details.OrderDateFormatted = formatDate(details.OrderDate, layout)
+	}
+
+	details.DocumentType = detectDocumentType(simpleText)
+	details.Signed = reSignatory.MatchString(simpleText)
+	details.CompositionScheme = reComposition.MatchString(simpleText)
+
+	// The seller's contact details are printed in the header, ahead of any buyer details.
+	details.match("contact_phone", &details.ContactPhone, rePhone, simpleText)
+	details.ContactEmail = reEmail.FindString(simpleText)
+	details.recordMatch("contact_email", reEmail, details.ContactEmail)
+	if details.ContactPhone != "" {
+		if e164, ok := normalizeE164(details.ContactPhone, activeConfig().DefaultCountryCode); ok {
+			details.ContactPhoneE164 = e164
+		} else {
+			details.warn(SeverityError, WarnInvalidFormat, "contact phone %q is not a valid phone number", details.ContactPhone)
+			details.ambiguous("contact phone %q is not a valid phone number", details.ContactPhone)
+		}
+	}
+
+	details.UPIID, details.UPIPaymentString = findUPI(simpleText)
+	details.CIN = findCIN(simpleText)
+	details.recordMatch("cin", reCIN, details.CIN)
+
+	details.parsePlaceOfSupply(simpleText)
+	// The request's patterns were validated before the extraction started.
+	customFields, _ := compileCustomFields(opts.CustomFields)
+	details.parseCustomFields(customFields, simpleText, columnText)
+	if maxNotes := activeConfig().MaxNotesSize; maxNotes > 0 {
+		details.parseNotes(simpleText, maxNotes)
+	}
+	details.parseAmounts(simpleText)
+	if len(details.LineItems) == 0 {
+		// Tables the simple layout scrambles may still line up in the column layout.
+		if items := parseLineItems(columnText); len(items) > 0 {
+			details.LineItems = items
+			reconcileLineTax(details)
+		}
+	}
+	if details.LineItems == nil {
+		details.LineItems = []LineItem{}
+	}
+	details.parseForeignTotal(simpleText)
+	details.parsePayments(simpleText)
+	details.parseBreakdown(simpleText)
+	parseDocumentCounts(details, simpleText)
+	details.HSNSummary = parseHSNSummary(simpleText)
+
 	// --- Parse the multi-line billing block from the 'columns' text layout ---
 	if billingBlockMatch := reBillingBlock.FindStringSubmatch(columnText); len(billingBlockMatch) > 1 {
 		billingBlockText := billingBlockMatch[1]
-		name, address, gst := parseBillingBlock(billingBlockText)
+		name, address, gst := parseBillingBlock(billingBlockText, activeConfig().gstLabel, activeConfig().billingLabel, activeConfig().addressEnd)
 		details.BillingName = name
-		details.BillingAddress = address
-		// Avoid capturing the seller's GST as the client's.
-		if !strings.EqualFold(gst, sellerGSTIN) {
+		details.BillingNameType = classifyName(name)
+		details.BillingAddress = strings.Join(address, ", ")
+		if activeConfig().AddressLines {
+			details.BillingAddressLines = address
+		}
+		details.recordMatch("billing_name", reBillingBlock, name)
+		details.recordMatch("billing_address", reBillingBlock, details.BillingAddress)
+		details.parseBillingContacts(billingBlockText)
+		// Avoid capturing the seller's GST as the client's, or text that is
+		// not a GSTIN at all.
+		switch {
+		case isSellerGSTIN(gst):
+		case gst != "" && !ValidateGSTIN(gst):
+			details.warn(SeverityError, WarnInvalidFormat, "client GSTIN %q is not a valid GSTIN and was discarded", gst)
+			details.ambiguous("gst_no_client %q is not a valid GSTIN", gst)
+		default:
 			details.GSTNOClient = gst
+			details.recordMatch("gst_no_client", activeConfig().gstLabel, gst)
 		}
 	}
-	// DEBUG: Print the extracted details as JSON
-	jsonData, err := json.MarshalIndent(details, "", "  ")
-	if err != nil {
-		fmt.Println("Failed to marshal details to JSON:", err)
-	} else {
-		fmt.Println("Extracted InvoiceDetails (JSON):")
-		fmt.Println(string(jsonData))
+	// Collect every GSTIN by party. When the billing block had none, the buyer's
+	// labelled GSTIN elsewhere in the document is the client's.
+	details.GSTINs = findGSTINs(columnText)
+	if details.GSTNOClient == "" {
+		for _, g := range details.GSTINs {
+			if g.Party == PartyBuyer && g.Valid {
+				details.GSTNOClient = g.Number
+				details.recordMatch("gst_no_client", reGSTINToken, g.Number)
+				details.doubt("gst_no_client", doubtOffPosition)
+				break
+			}
+		}
+	}
+
+	details.comparePlaceOfSupply()
+	details.Extracted = details.PopulatedFields() >= activeConfig().MinPopulatedFields
+	details.MissingFields = details.missingFields()
+	details.scoreConfidence()
+	details.noteMissingFields()
+
+	details.reportChecks(activeConfig().Validate)
+
+	// Log the full result for development, only when debug logging is enabled.
+	if log(ctx).Enabled(ctx, slog.LevelDebug) {
+		jsonData, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			log(ctx).Debug("failed to marshal details to JSON", "error", err)
+		} else {
+			log(ctx).Debug("extracted invoice details", "details", string(jsonData))
+		}
 	}
 
 	return details, nil
@@ -119,13 +687,24 @@ func ExtractDetails(file io.Reader) (*InvoiceDetails, error) {
 // extractTextWithPython securely executes an external Python script to extract text from a PDF.
 // It creates a temporary file for the PDF content and passes its path to the script.
 // It returns the script's stdout or an error containing stderr for easier debugging.
+// With Config.PythonWorkers set, the request goes to a pooled worker process
+// instead of a fresh interpreter.
 //
 // Parameters:
+//   - ctx: Cancelling it kills the Python process, pooled or not.
 //   - reader: An io.Reader providing the PDF file content.
-//   - mode: The extraction mode ('simple' or 'columns') to pass to the Python script.
-func extractTextWithPython(reader io.Reader, mode string) (string, error) {
+//   - mode: The extraction mode ('simple', 'columns' or 'ocr') to pass to the Python script.
+//   - pages: Optional 1-based page numbers to process; nil lets the script pick the last page.
+//   - engine: The library the script reads the text with (see Engines); "" uses its default.
+func extractTextWithPython(ctx context.Context, reader io.Reader, mode string, pages []int, engine string) (string, error) {
+	// Check the flags before doing any work, so a bad value never reaches the command line.
+	flags, err := scriptArgs(mode, pages, engine)
+	if err != nil {
+		return "", err
+	}
+
 	// Create a temporary file to hold the PDF content. This is safer than passing raw bytes.
-	tmpFile, err := os.CreateTemp("", "invoice-*.pdf")
+	tmpFile, err := os.CreateTemp("", tempPDFPattern)
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -139,67 +718,104 @@ func extractTextWithPython(reader io.Reader, mode string) (string, error) {
 		return "", fmt.Errorf("failed to close temp file: %w", err)
 	}
 
-	// Sanitize the script path to prevent directory traversal vulnerabilities.
-	scriptPath, err := filepath.Abs(filepath.Join("tools", "pdf_text_extractor.py"))
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve absolute script path: %w", err)
+	if pool := pythonPool(); pool != nil {
+		text, err := pool.extract(ctx, workerRequest{PDFPath: tmpFile.Name(), Mode: mode, Pages: pages, Engine: engine})
+		if err != nil {
+			return "", classifyScriptError(fmt.Errorf("python worker failed (mode: %s, engine: %s): %w", mode, cmp.Or(engine, defaultEngine), err), err.Error())
+		}
+		return text, nil
 	}
 
-	cmd := exec.Command("./tools/venv/bin/python3", scriptPath, tmpFile.Name(), "--mode="+mode)
+	cfg := activeConfig()
+	args := append([]string{cfg.scriptPath, tmpFile.Name()}, flags...)
+
+	cmd := exec.CommandContext(ctx, cfg.PythonPath, args...)
 	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr // Capture stderr for better error reporting.
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("python script failed (mode: %s): %w. Stderr: %s", mode, err, stderr.String())
+		err = fmt.Errorf("python script failed (mode: %s, engine: %s): %w. Stderr: %s", mode, cmp.Or(engine, defaultEngine), err, stderr.String())
+		return "", classifyScriptError(err, stderr.String())
 	}
 
 	return out.String(), nil
 }
 
 // parseBillingBlock takes the raw text of the billing address section and extracts
-// the name, full address, and the client's GST number (if present).
-func parseBillingBlock(blockText string) (name, address, gst string) {
+// the name, the address lines, and the client's GST number (if present). Lines matching
+// reGST are taken as the GST line and left out of the address. A leading label
+// matching reLabel, such as "Bill To:", is stripped; reLabel may be nil. The
+// address is cut after the line end recognizes as its last.
+func parseBillingBlock(blockText string, reGST, reLabel *regexp.Regexp, end addressEnd) (name string, address []string, gst string) {
 	lines := strings.Split(blockText, "\n")
 	var addressParts []string
-	foundAddressEnd := false
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
+		if reLabel != nil {
+			line = strings.TrimSpace(reLabel.ReplaceAllString(line, ""))
+		}
 		if line == "" {
 			continue
 		}
 
 		// Check for the client's GSTIN, which can appear within the address block.
-		if strings.Contains(strings.ToLower(line), "gst registration no") {
+		if reGST.MatchString(line) {
 			gst = findStringSubmatchAndClean(reGST, line, 1)
 			continue // Don't include the GST line in the address itself.
 		}
 
-		// The address often ends with a country code like "IN" or "CA".
-		// We stop capturing lines after we find it.
-		if !foundAddressEnd {
-			addressParts = append(addressParts, line)
-			if line == "IN" || line == "CA" {
-				foundAddressEnd = true
-			}
-		}
+		addressParts = append(addressParts, line)
 	}
+	addressParts = end.trim(addressParts)
 
 	if len(addressParts) > 0 {
 		name = addressParts[0]
 	}
 	if len(addressParts) > 1 {
-		address = strings.Join(addressParts[1:], ", ")
+		address = addressParts[1:]
 	}
 
 	return name, address, gst
 }
 
+// detectDocumentType classifies the document from its title wording.
+// Anything that is not explicitly a credit or debit note is treated as an invoice.
+func detectDocumentType(text string) string {
+	switch {
+	case reCreditNote.MatchString(text):
+		return DocumentTypeCreditNote
+	case reDebitNote.MatchString(text):
+		return DocumentTypeDebitNote
+	default:
+		return DocumentTypeInvoice
+	}
+}
+
+// signedAmount parses a printed amount and applies the sign implied by the document.
+// Credit notes usually print their amounts as positive figures even though they
+// represent money owed back, so those are negated unless already negative.
+func signedAmount(printed, documentType string) float64 {
+	value, ok := parseAmount(printed)
+	if !ok {
+		return 0
+	}
+	if documentType == DocumentTypeCreditNote && value > 0 {
+		value = -value
+	}
+	return value
+}
+
 // findStringSubmatchAndClean is a helper function that applies a regex to a text,
 // extracts a specific capture group, and cleans up whitespace.
 func findStringSubmatchAndClean(re *regexp.Regexp, text string, group int) string {
-	match := re.FindStringSubmatch(text)
+	return cleanMatch(re.FindStringSubmatch(text), group)
+}
+
+// cleanMatch returns the given group of a submatch with its whitespace collapsed,
+// or "" when the match is nil or lacks the group.
+func cleanMatch(match []string, group int) string {
 	if len(match) > group {
 		// Replace newlines and multiple spaces with a single space for consistency.
 		cleaned := strings.ReplaceAll(match[group], "\n", " ")
@@ -0,0 +1,91 @@
+package extractor
+
+import (
+	"regexp"
+	"slices"
+)
+
+// FieldInfo describes how one InvoiceDetails field is extracted, for tooling
+// that inspects or tunes the extractor without reading its source.
+type FieldInfo struct {
+	// Name is the field's JSON name.
+	Name string `json:"name"`
+	// Patterns are the regular expressions tried for the field, in order.
+	Patterns []string `json:"patterns"`
+	// Mode is the text layout the field is read from: "simple" or "columns".
+	Mode string `json:"mode"`
+	// CrossChecked is set when the value is also looked up in the other
+	// layout, and a disagreement is reported as a warning.
+	CrossChecked bool `json:"cross_checked"`
+	// Overridden is set when the patterns derive from configuration that
+	// differs from DefaultConfig.
+	Overridden bool `json:"overridden"`
+}
+
+// Fields lists the pattern-driven fields of InvoiceDetails and how each is
+// extracted under the active configuration. Derived fields, such as the
+// numeric amounts or fiscal period, are not listed.
+func Fields() []FieldInfo {
+	cfg := activeConfig()
+	defaults := DefaultConfig()
+	totalsOverridden := !slices.Equal(cfg.TotalLabels, defaults.TotalLabels)
+	gstOverridden := !slices.Equal(cfg.GSTLabels, defaults.GSTLabels)
+
+	invoiceNumber := []*regexp.Regexp{reInvoiceNumber}
+	if cfg.invoiceNumberShape != nil {
+		invoiceNumber = append(invoiceNumber, cfg.invoiceNumberShape)
+	}
+
+	asn := []*regexp.Regexp{reASN}
+	if cfg.productCode != nil {
+		asn = []*regexp.Regexp{cfg.productCode, reASN}
+	}
+
+	crossChecked := func(name string, res ...*regexp.Regexp) FieldInfo {
+		return FieldInfo{Name: name, Patterns: patternStrings(res), Mode: "simple", CrossChecked: true}
+	}
+	fields := []FieldInfo{
+		crossChecked("invoice_number", invoiceNumber...),
+		crossChecked("invoice_date", reInvoiceDate),
+		crossChecked("order_number", reOrderNo),
+		crossChecked("order_date", reOrderDate),
+		crossChecked("state_code", reStateCode),
+		crossChecked("hsn", reHSN),
+		crossChecked("asn", asn...),
+		crossChecked("challan_number", reChallan, reDeliveryNote),
+		crossChecked("reference_number", reReferenceNo),
+		{Name: "place_of_supply", Patterns: patternStrings([]*regexp.Regexp{rePlaceOfSupply, rePlaceOfSupplyCode}), Mode: "simple"},
+		{Name: "notes", Patterns: patternStrings([]*regexp.Regexp{reNotesHeading}), Mode: "simple"},
+		{Name: "contact_phone", Patterns: patternStrings([]*regexp.Regexp{rePhone}), Mode: "simple"},
+		{Name: "contact_email", Patterns: patternStrings([]*regexp.Regexp{reEmail}), Mode: "simple"},
+		{Name: "tax_amount", Patterns: patternStrings(cfg.totalLabels), Mode: "simple", Overridden: totalsOverridden},
+		{Name: "total_amount", Patterns: patternStrings(cfg.totalLabels), Mode: "simple", Overridden: totalsOverridden},
+		{Name: "cgst", Patterns: patternStrings([]*regexp.Regexp{reGSTComponent}), Mode: "simple"},
+		{Name: "sgst", Patterns: patternStrings([]*regexp.Regexp{reGSTComponent}), Mode: "simple"},
+		{Name: "igst", Patterns: patternStrings([]*regexp.Regexp{reGSTComponent}), Mode: "simple"},
+		{Name: "billing_name", Patterns: patternStrings([]*regexp.Regexp{reBillingBlock}), Mode: "columns"},
+		{Name: "billing_address", Patterns: patternStrings([]*regexp.Regexp{reBillingBlock}), Mode: "columns"},
+		{Name: "billing_phone", Patterns: patternStrings([]*regexp.Regexp{reBillingBlock, rePhone}), Mode: "columns"},
+		{Name: "billing_email", Patterns: patternStrings([]*regexp.Regexp{reBillingBlock, reEmail}), Mode: "columns"},
+		{Name: "gst_no_client", Patterns: patternStrings([]*regexp.Regexp{cfg.gstLabel, reGSTINToken}), Mode: "columns", Overridden: gstOverridden},
+	}
+	for i := range fields {
+		switch fields[i].Name {
+		case "invoice_number":
+			fields[i].Overridden = cfg.InvoiceNumberShape != defaults.InvoiceNumberShape
+		case "asn":
+			fields[i].Overridden = !slices.Equal(cfg.ProductCodeLabels, defaults.ProductCodeLabels) ||
+				cfg.ProductCodePattern != defaults.ProductCodePattern
+		}
+	}
+	return fields
+}
+
+// patternStrings returns the source text of each pattern.
+func patternStrings(res []*regexp.Regexp) []string {
+	patterns := make([]string, len(res))
+	for i, re := range res {
+		patterns[i] = re.String()
+	}
+	return patterns
+}
@@ -0,0 +1,53 @@
+package extractor
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Flatten returns the details as a flat map of field name to value, for consumers
+// that cannot handle nested JSON. Keys are the JSON field names; nested values
+// get dotted keys such as "line_items.0.amount" or "normalized_ids.invoice_number".
+// Numbers and booleans are rendered as their JSON text, and omitted or null
+// values have no key.
+func (d *InvoiceDetails) Flatten() (map[string]string, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]string)
+	flattenInto(flat, "", tree)
+	return flat, nil
+}
+
+// flattenInto adds the leaves of the decoded JSON value v to flat under prefix.
+func flattenInto(flat map[string]string, prefix string, v any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flattenInto(flat, join(key), child)
+		}
+	case []any:
+		for i, child := range v {
+			flattenInto(flat, join(strconv.Itoa(i)), child)
+		}
+	case string:
+		flat[prefix] = v
+	case float64:
+		flat[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		flat[prefix] = strconv.FormatBool(v)
+	}
+}
@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Parties a GSTIN can be attributed to in InvoiceDetails.GSTINs.
+const (
+	PartySeller    = "seller"
+	PartyBuyer     = "buyer"
+	PartyConsignee = "consignee"
+	PartyUnknown   = "unknown"
+)
+
+// PartyGSTIN is a GSTIN found in the document together with the party it belongs to.
+// Valid reports whether Number passes ValidateGSTIN; numbers that fail, usually
+// misread by OCR, are still listed so they can be reviewed.
+type PartyGSTIN struct {
+	Party  string `json:"party"`
+	Number string `json:"number"`
+	Valid  bool   `json:"valid"`
+}
+
+var (
+	// reGSTINToken matches the structure of a GSTIN: state code, PAN, entity
+	// number, the fixed "Z" and a check character.
+	reGSTINToken = regexp.MustCompile(`\b\d{2}[A-Z]{5}\d{4}[A-Z][1-9A-Z]Z[0-9A-Z]\b`)
+
+	// rePartyHeader matches the headings that introduce each party's block.
+	rePartyHeader = regexp.MustCompile(`(?i)\b(Bill(?:ed|ing)?\s+To|Billing\s+Address|Buyer|Ship(?:ped|ping)?\s+To|Shipping\s+Address|Consignee|Sold\s+By|Seller|Supplier)\b`)
+)
+
+// gstinCharset orders the characters of a GSTIN by their value in the
+// checksum: digits first, then letters.
+const gstinCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// ValidateGSTIN reports whether s is a well-formed GSTIN: a valid two-digit
+// state code, a PAN, an entity number, the fixed "Z", and a check character
+// matching the other fourteen. Lower-case letters are not accepted.
+//
+// The check character is computed as the GST portal does: each character's
+// value (0-9 for digits, 10-35 for A-Z) is multiplied by 1 or 2 alternately,
+// starting with 1, each product is reduced to the sum of its base-36 digits,
+// and the check character is the value that brings the total to a multiple
+// of 36.
+func ValidateGSTIN(s string) bool {
+	if len(s) != 15 || !reGSTINToken.MatchString(s) || !validStateCode(s[:2]) {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 14; i++ {
+		product := strings.IndexByte(gstinCharset, s[i]) * (1 + i%2)
+		sum += product/36 + product%36
+	}
+	return gstinCharset[(36-sum%36)%36] == s[14]
+}
+
+// findGSTINs returns every distinct GSTIN in text, labelled with the party whose
+// heading most closely precedes it and validated with ValidateGSTIN. Known
+// seller GSTINs are always labelled as the seller's, wherever they appear.
+func findGSTINs(text string) []PartyGSTIN {
+	headers := rePartyHeader.FindAllStringSubmatchIndex(text, -1)
+
+	var found []PartyGSTIN
+	seen := make(map[string]bool)
+	for _, loc := range reGSTINToken.FindAllStringIndex(text, -1) {
+		number := text[loc[0]:loc[1]]
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+
+		party := PartyUnknown
+		if isSellerGSTIN(number) {
+			party = PartySeller
+		} else {
+			for _, h := range headers {
+				if h[1] > loc[0] {
+					break
+				}
+				party = partyForHeader(text[h[2]:h[3]])
+			}
+		}
+		found = append(found, PartyGSTIN{Party: party, Number: number, Valid: ValidateGSTIN(number)})
+	}
+	return found
+}
+
+// partyForHeader maps a party heading to the party it introduces.
+func partyForHeader(header string) string {
+	header = strings.ToLower(header)
+	switch {
+	case strings.HasPrefix(header, "bill"), header == "buyer":
+		return PartyBuyer
+	case strings.HasPrefix(header, "ship"), header == "consignee":
+		return PartyConsignee
+	default:
+		return PartySeller
+	}
+}
+
+// isSellerGSTIN reports whether number is one of the seller's own GSTINs, as
+// configured in Config.SellerGSTINs.
+func isSellerGSTIN(number string) bool {
+	return activeConfig().sellerGSTINs[strings.ToUpper(number)]
+}
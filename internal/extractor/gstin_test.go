@@ -0,0 +1,65 @@
+package extractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateGSTIN(t *testing.T) {
+	tests := []struct {
+		gstin string
+		want  bool
+	}{
+		{"27AAPFU0939F1ZV", true},
+		{"29AAGCB7383J1Z4", true},
+		{"07AAACI1681G1ZR", true},
+
+		{"27AAPFU0939F1ZW", false},  // wrong check character
+		{"27AAPFU0939F1Z0", false},  // wrong check character
+		{"29AAGCB7383J1ZV", false},  // check character of another GSTIN
+		{"72AAPFU0939F1ZV", false},  // digits swapped: invalid state code
+		{"27AAPFU0939F1YV", false},  // "Y" in place of the fixed "Z"
+		{"27aapfu0939f1zv", false},  // lower case
+		{"27AAPFU0939F1Z", false},   // too short
+		{"27AAPFU0939F1ZVX", false}, // too long
+		{"00AAPFU0939F1ZV", false},  // state code 00
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidateGSTIN(tt.gstin); got != tt.want {
+			t.Errorf("ValidateGSTIN(%q) = %v, want %v", tt.gstin, got, tt.want)
+		}
+	}
+}
+
+// Every check character but the right one must be rejected, so a single
+// mistyped final character is always caught.
+func TestValidateGSTINCheckCharacter(t *testing.T) {
+	const base = "27AAPFU0939F1Z"
+	valid := 0
+	for i := 0; i < len(gstinCharset); i++ {
+		if ValidateGSTIN(base + gstinCharset[i:i+1]) {
+			valid++
+		}
+	}
+	if valid != 1 {
+		t.Errorf("%d check characters accepted for %s, want 1", valid, base)
+	}
+}
+
+func TestFindGSTINs(t *testing.T) {
+	text := "Sold By\nACME Traders GSTIN: 19APGPS1824K1ZI\n" +
+		"Bill To\nBuyer Pvt Ltd GSTIN: 27AAPFU0939F1ZV\n" +
+		"Ship To\nWarehouse GSTIN: 29AAGCB7383J1ZV\n" +
+		"Branch 07AAACI1681G1ZR\n" +
+		"Bill To again 27AAPFU0939F1ZV"
+	want := []PartyGSTIN{
+		{Party: PartySeller, Number: "19APGPS1824K1ZI", Valid: true},
+		{Party: PartyBuyer, Number: "27AAPFU0939F1ZV", Valid: true},
+		{Party: PartyConsignee, Number: "29AAGCB7383J1ZV", Valid: false},
+		{Party: PartyConsignee, Number: "07AAACI1681G1ZR", Valid: true},
+	}
+	if got := findGSTINs(text); !slices.Equal(got, want) {
+		t.Errorf("findGSTINs() = %+v, want %+v", got, want)
+	}
+}
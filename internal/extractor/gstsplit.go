@@ -0,0 +1,63 @@
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// reGSTComponent finds the labels of the GST components. UTGST, charged in
+// union territories in place of SGST, is read as SGST.
+var reGSTComponent = regexp.MustCompile(`\b(CGST|SGST|UTGST|IGST)\b`)
+
+// parseGSTSplit reads the CGST, SGST and IGST amounts from the lines of text
+// that label them, as in "CGST @ 9% 90.00" or "Add: IGST (18%) : 180.00".
+// Several components may share a line; each owns the text up to the next
+// label. When a component is printed more than once, the last amount wins,
+// as a summary line follows the lines it sums. Labels without an amount,
+// such as table headers, are skipped.
+//
+// When the total line carried no separate tax, TaxAmount becomes the sum of
+// the components. IsInterState is set when IGST alone is charged.
+func (d *InvoiceDetails) parseGSTSplit(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		labels := reGSTComponent.FindAllStringSubmatchIndex(line, -1)
+		for i, m := range labels {
+			end := len(line)
+			if i+1 < len(labels) {
+				end = labels[i+1][0]
+			}
+			segment := reTaxRate.ReplaceAllString(line[m[1]:end], " ")
+			amounts := reAmount.FindAllString(segment, -1)
+			if len(amounts) == 0 {
+				continue
+			}
+			field, dst := "igst", &d.IGST
+			switch line[m[2]:m[3]] {
+			case "CGST":
+				field, dst = "cgst", &d.CGST
+			case "SGST", "UTGST":
+				field, dst = "sgst", &d.SGST
+			}
+			*dst = amounts[len(amounts)-1]
+			d.recordMatch(field, reGSTComponent, *dst)
+		}
+	}
+
+	d.IsInterState = d.IGST != "" && d.CGST == "" && d.SGST == ""
+
+	if d.TaxAmount != "" {
+		return
+	}
+	var sum float64
+	var found bool
+	for _, component := range []string{d.CGST, d.SGST, d.IGST} {
+		if v, ok := parseAmount(component); ok {
+			sum += v
+			found = true
+		}
+	}
+	if found {
+		d.TaxAmount = strconv.FormatFloat(sum, 'f', activeConfig().AmountPrecision, 64)
+	}
+}
@@ -0,0 +1,86 @@
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HSNSummaryRow is one row of the HSN-wise summary table printed on GST invoices,
+// aggregating the invoice by HSN/SAC code as required for the GSTR-1 return.
+type HSNSummaryRow struct {
+	HSN          string  `json:"hsn"`
+	UQC          string  `json:"uqc"` // Unit quantity code, e.g. "NOS" or "KGS".
+	Quantity     float64 `json:"quantity"`
+	TaxableValue float64 `json:"taxable_value"`
+	Tax          float64 `json:"tax"` // All tax components on the row combined.
+}
+
+var (
+	// reHSNSummaryHeader matches the heading line of the HSN summary table, which
+	// names the HSN column and the taxable value column.
+	reHSNSummaryHeader = regexp.MustCompile(`(?i)\bHSN(?:/SAC)?\b.*\bTaxable\b`)
+	reHSNSummaryRow    = regexp.MustCompile(`^\s*(\d{4,8})\b(.*)$`)
+	reHSNSummaryEnd    = regexp.MustCompile(`(?i)^\s*(?:Total\b|Amount\s+in\s+Words|Tax\s+Amount\s+in\s+Words)`)
+	reUQC              = regexp.MustCompile(`\b([A-Z]{3})\b`)
+)
+
+// parseHSNSummary finds the HSN summary table in text and returns its rows. The
+// table starts after its heading line and ends at the first total line or the
+// first line that is not a row. It returns nil when no table is recognised.
+//
+// Within a row, the first amount is the taxable value and, when there are more,
+// the last is the total tax; rate percentages and per-component amounts in
+// between are ignored. A bare number is the quantity and a three-letter code the UQC.
+func parseHSNSummary(text string) []HSNSummaryRow {
+	var rows []HSNSummaryRow
+	inTable := false
+	for _, line := range strings.Split(text, "\n") {
+		if !inTable {
+			inTable = reHSNSummaryHeader.MatchString(line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if reHSNSummaryEnd.MatchString(line) {
+			break
+		}
+		match := reHSNSummaryRow.FindStringSubmatch(line)
+		if match == nil {
+			// Continuation of a wrapped heading or the end of the table.
+			if len(rows) > 0 {
+				break
+			}
+			continue
+		}
+		if row, ok := parseHSNSummaryRow(match[1], match[2]); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// parseHSNSummaryRow parses the columns that follow the HSN code of a summary row.
+func parseHSNSummaryRow(hsn, rest string) (HSNSummaryRow, bool) {
+	amounts := reAmount.FindAllString(rest, -1)
+	if len(amounts) == 0 {
+		return HSNSummaryRow{}, false
+	}
+
+	row := HSNSummaryRow{HSN: hsn}
+	row.TaxableValue, _ = parseAmount(amounts[0])
+	if len(amounts) > 1 {
+		row.Tax, _ = parseAmount(amounts[len(amounts)-1])
+	}
+
+	// Blank out amounts and rates so only the quantity and UQC remain.
+	rest = reTaxRate.ReplaceAllString(reAmount.ReplaceAllString(rest, " "), " ")
+	if uqc := reUQC.FindStringSubmatch(rest); uqc != nil {
+		row.UQC = uqc[1]
+	}
+	if qty := reQuantity.FindStringSubmatch(rest); qty != nil {
+		row.Quantity, _ = strconv.ParseFloat(qty[1], 64)
+	}
+	return row, true
+}
@@ -0,0 +1,78 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IDRule describes how an ID field is normalized for systems that store IDs
+// without vendor formatting. Prefixes are stripped first, then leading zeros.
+type IDRule struct {
+	StripPrefixes     []string // Removed case-insensitively; the first match wins.
+	StripLeadingZeros bool
+}
+
+// IDFields lists the JSON names of the fields IDRules may apply to.
+var IDFields = []string{"invoice_number", "order_number", "challan_number", "reference_number"}
+
+// idValue returns the raw value of the ID field with the given JSON name.
+func (d *InvoiceDetails) idValue(field string) string {
+	switch field {
+	case "invoice_number":
+		return d.InvoiceNumber
+	case "order_number":
+		return d.OrderNumber
+	case "challan_number":
+		return d.ChallanNumber
+	case "reference_number":
+		return d.ReferenceNumber
+	}
+	return ""
+}
+
+// normalizeIDs applies the configured ID rules, reporting the normalized values
+// alongside the raw ones in NormalizedIDs.
+func (d *InvoiceDetails) normalizeIDs(rules map[string]IDRule) {
+	for field, rule := range rules {
+		raw := d.idValue(field)
+		if raw == "" {
+			continue
+		}
+		if d.NormalizedIDs == nil {
+			d.NormalizedIDs = make(map[string]string)
+		}
+		d.NormalizedIDs[field] = rule.apply(raw)
+	}
+}
+
+// apply normalizes a single ID value.
+func (rule IDRule) apply(id string) string {
+	for _, prefix := range rule.StripPrefixes {
+		if len(id) >= len(prefix) && strings.EqualFold(id[:len(prefix)], prefix) {
+			id = id[len(prefix):]
+			break
+		}
+	}
+	if rule.StripLeadingZeros {
+		if trimmed := strings.TrimLeft(id, "0"); trimmed != "" {
+			id = trimmed
+		} else if id != "" {
+			id = "0"
+		}
+	}
+	return id
+}
+
+// validateIDRules rejects rules for fields that are not ID fields.
+func validateIDRules(rules map[string]IDRule) error {
+	for field := range rules {
+		known := false
+		for _, f := range IDFields {
+			known = known || f == field
+		}
+		if !known {
+			return fmt.Errorf("ID rule for unknown field %q; valid fields are %s", field, strings.Join(IDFields, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package extractor
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestIDRuleApply(t *testing.T) {
+	tests := []struct {
+		name string
+		rule IDRule
+		id   string
+		want string
+	}{
+		{"no rule", IDRule{}, "INV-000123", "INV-000123"},
+		{"leading zeros", IDRule{StripLeadingZeros: true}, "000123", "123"},
+		{"all zeros keep one", IDRule{StripLeadingZeros: true}, "0000", "0"},
+		{"inner zeros kept", IDRule{StripLeadingZeros: true}, "1002", "1002"},
+		{"prefix", IDRule{StripPrefixes: []string{"INV-"}}, "INV-000123", "000123"},
+		{"prefix ignores case", IDRule{StripPrefixes: []string{"inv-"}}, "INV-42", "42"},
+		{"prefix then zeros", IDRule{StripPrefixes: []string{"INV-"}, StripLeadingZeros: true}, "INV-000123", "123"},
+		{"first matching prefix wins", IDRule{StripPrefixes: []string{"INV/", "INV"}}, "INV/2024/7", "2024/7"},
+		{"only one prefix removed", IDRule{StripPrefixes: []string{"A", "B"}}, "AB12", "B12"},
+		{"prefix not at start", IDRule{StripPrefixes: []string{"INV-"}}, "X-INV-12", "X-INV-12"},
+		{"prefix longer than id", IDRule{StripPrefixes: []string{"INVOICE-"}}, "INV", "INV"},
+		{"zeros after a kept separator", IDRule{StripPrefixes: []string{"INV"}, StripLeadingZeros: true}, "INV-007", "-007"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.apply(tt.id); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+// The raw values stay untouched; only fields with a rule and a value are
+// reported in NormalizedIDs.
+func TestNormalizeIDs(t *testing.T) {
+	d := &InvoiceDetails{InvoiceNumber: "INV-000123", OrderNumber: "00042", ChallanNumber: "CH-9"}
+	d.normalizeIDs(map[string]IDRule{
+		"invoice_number":   {StripPrefixes: []string{"INV-"}, StripLeadingZeros: true},
+		"order_number":     {StripLeadingZeros: true},
+		"reference_number": {StripLeadingZeros: true},
+	})
+
+	want := map[string]string{"invoice_number": "123", "order_number": "42"}
+	if !maps.Equal(d.NormalizedIDs, want) {
+		t.Errorf("NormalizedIDs = %v, want %v", d.NormalizedIDs, want)
+	}
+	if d.InvoiceNumber != "INV-000123" || d.OrderNumber != "00042" || d.ChallanNumber != "CH-9" {
+		t.Errorf("raw IDs changed: %q, %q, %q", d.InvoiceNumber, d.OrderNumber, d.ChallanNumber)
+	}
+
+	d = &InvoiceDetails{InvoiceNumber: "INV-1"}
+	d.normalizeIDs(nil)
+	if d.NormalizedIDs != nil {
+		t.Errorf("NormalizedIDs = %v without rules, want nil", d.NormalizedIDs)
+	}
+}
@@ -0,0 +1,21 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// guessInvoiceNumber looks for a token of the given shape within the first
+// lines of text, where invoices print their number when they don't label it.
+// It returns the shape's capture group when it has one, otherwise the whole match.
+func guessInvoiceNumber(text string, shape *regexp.Regexp, lines int) string {
+	top := strings.SplitN(text, "\n", lines+1)
+	if len(top) > lines {
+		top = top[:lines]
+	}
+	match := shape.FindStringSubmatch(strings.Join(top, "\n"))
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(match[len(match)-1])
+}
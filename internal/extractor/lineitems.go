@@ -0,0 +1,194 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineItem is one row of the invoice's item table. Values are kept as printed.
+type LineItem struct {
+	Description string `json:"description"`
+	Quantity    string `json:"quantity"`
+	UnitPrice   string `json:"unit_price"`
+	HSN         string `json:"hsn"`
+	Amount      string `json:"amount"`
+	TaxRate     string `json:"tax_rate"`   // e.g. "18%"
+	TaxAmount   string `json:"tax_amount"` // The GST charged on this line.
+}
+
+var (
+	reTableHeader = regexp.MustCompile(`(?i)\b(?:Description|Particulars)\b`)
+	reTableEnd    = regexp.MustCompile(`(?i)^\s*(?:(?:Sub\s*|Grand\s+)?Total\b|Amount\s+in\s+Words)`)
+	reItemRow     = regexp.MustCompile(`^\s*(\d{1,3})[.)]?\s+(.+)$`)
+	reItemHSN     = regexp.MustCompile(`(?i)\bHSN(?:/SAC)?\s*:?\s*(\d{4,8})\b`)
+	reTaxRate     = regexp.MustCompile(`(\d{1,2}(?:\.\d+)?)\s*%`)
+	reQuantity    = regexp.MustCompile(`(?:^|\s)(\d+(?:\.\d{1,3})?)(?:\s|$)`)
+	// reLeadingQuantity matches a bare number ending the text before the unit
+	// price, where the quantity column sits in "Description Qty Rate Amount"
+	// tables. Group 1 is the separator before it and group 2 the number.
+	reLeadingQuantity = regexp.MustCompile(`(\s{2,}|\|\s*|\s)(\d+(?:\.\d{1,3})?)[\s|]*$`)
+
+	reTotalQuantity = regexp.MustCompile(`(?i)\bTotal\s+(?:Quantity|Qty)\.?\s*[:\-]?\s*(\d+(?:\.\d{1,3})?)\b`)
+	reItemCount     = regexp.MustCompile(`(?i)\b(?:Total\s+Items|No\.?\s+of\s+Items|Item\s+Count)\s*[:\-]?\s*(\d+)\b`)
+)
+
+// parseLineItems walks the item table of a text layout, where each row stays on
+// one line, and returns its rows. The table starts after the line
+// holding the Description heading and ends at the first total line.
+// Long descriptions wrap onto continuation lines, which carry no serial number,
+// HSN or amount; those are appended to the description of the row above.
+// It returns nil when no table is recognised.
+func parseLineItems(text string) []LineItem {
+	var items []LineItem
+	inTable := false
+	for _, line := range strings.Split(text, "\n") {
+		if !inTable {
+			inTable = reTableHeader.MatchString(line)
+			continue
+		}
+		if reTableEnd.MatchString(line) {
+			break
+		}
+		if row := reItemRow.FindStringSubmatch(line); row != nil {
+			if item, ok := parseItemRow(row[2]); ok {
+				items = append(items, item)
+				continue
+			}
+		}
+		if len(items) > 0 && isContinuationLine(line) {
+			last := &items[len(items)-1]
+			last.Description = strings.TrimSpace(last.Description + " " + strings.Trim(strings.TrimSpace(line), "| "))
+		}
+	}
+	return items
+}
+
+// isContinuationLine reports whether line of the item table only continues the
+// description of the row above it.
+func isContinuationLine(line string) bool {
+	trimmed := strings.Trim(strings.TrimSpace(line), "| ")
+	return trimmed != "" &&
+		!reItemRow.MatchString(line) &&
+		!reItemHSN.MatchString(line) &&
+		!reAmount.MatchString(line)
+}
+
+// parseItemRow splits a table row, without its serial number, into a LineItem.
+// Columns are told apart by shape: the first amount is the unit price and the
+// last the line amount, the amount following the tax rate is the line's tax,
+// and the first bare number after the unit price is the quantity. Failing
+// that, a bare number just before the unit price is the quantity, when it is
+// set apart as a column or multiplies with the unit price to one of the
+// row's amounts, so that a number ending the description is not taken for it.
+func parseItemRow(row string) (LineItem, bool) {
+	amounts := reAmount.FindAllStringIndex(row, -1)
+	if len(amounts) == 0 {
+		return LineItem{}, false
+	}
+
+	var item LineItem
+	descEnd := amounts[0][0]
+	hsn := reItemHSN.FindStringSubmatchIndex(row)
+	if hsn != nil {
+		item.HSN = row[hsn[2]:hsn[3]]
+		descEnd = min(descEnd, hsn[0])
+	}
+	item.UnitPrice = row[amounts[0][0]:amounts[0][1]]
+
+	// Blank out amounts and rates so only the quantity remains as a bare number.
+	rest := reTaxRate.ReplaceAllString(reAmount.ReplaceAllString(row[amounts[0][1]:], " "), " ")
+	if qty := reQuantity.FindStringSubmatch(rest); qty != nil {
+		item.Quantity = qty[1]
+	} else {
+		before := row[:amounts[0][0]]
+		if hsn != nil && hsn[1] <= len(before) {
+			before = before[:hsn[0]] + strings.Repeat(" ", hsn[1]-hsn[0]) + before[hsn[1]:]
+		}
+		if m := reLeadingQuantity.FindStringSubmatchIndex(before); m != nil && m[2] > 0 {
+			qty := before[m[4]:m[5]]
+			if before[m[2]:m[3]] != " " || multipliesToAmount(qty, item.UnitPrice, row, amounts[1:]) {
+				item.Quantity = qty
+				descEnd = min(descEnd, m[0])
+			}
+		}
+	}
+
+	item.Description = strings.Trim(strings.TrimSpace(row[:descEnd]), "|₹- ")
+	item.Amount = row[amounts[len(amounts)-1][0]:amounts[len(amounts)-1][1]]
+
+	if rate := reTaxRate.FindStringSubmatchIndex(row); rate != nil {
+		item.TaxRate = row[rate[2]:rate[3]] + "%"
+		for _, a := range amounts {
+			if a[0] > rate[1] {
+				item.TaxAmount = row[a[0]:a[1]]
+				break
+			}
+		}
+	}
+
+	return item, true
+}
+
+// multipliesToAmount reports whether quantity times unitPrice is, to the paisa,
+// one of the amounts of row at the given indexes.
+func multipliesToAmount(quantity, unitPrice, row string, amounts [][]int) bool {
+	q, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return false
+	}
+	price, ok := parseAmount(unitPrice)
+	if !ok {
+		return false
+	}
+	for _, a := range amounts {
+		if v, ok := parseAmount(row[a[0]:a[1]]); ok && math.Abs(q*price-v) < 0.01 {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileLineTax records a mismatch when the per-line taxes don't add up to the
+// document tax. Composition-scheme invoices charge no tax, so there is nothing
+// to reconcile.
+func reconcileLineTax(d *InvoiceDetails) {
+	if d.TaxAmountValue == 0 || d.CompositionScheme {
+		return
+	}
+
+	var sum float64
+	taxed := 0
+	for _, item := range d.LineItems {
+		if v, ok := parseAmount(item.TaxAmount); ok {
+			sum += v
+			taxed++
+		}
+	}
+	if taxed == 0 {
+		return
+	}
+	// Allow a paisa of rounding per line.
+	if math.Abs(math.Abs(sum)-math.Abs(d.TaxAmountValue)) > 0.01*float64(taxed) {
+		d.mismatch(CheckLineItemsTax, WarnLineItemTaxMismatch, "line item taxes sum to %.2f but the document tax is %.2f", sum, math.Abs(d.TaxAmountValue))
+	}
+}
+
+// parseDocumentCounts reads the document-level total quantity and item count,
+// and warns when the stated count disagrees with the parsed line items, which
+// means rows were missed or split.
+func parseDocumentCounts(d *InvoiceDetails, text string) {
+	if m := reTotalQuantity.FindStringSubmatch(text); m != nil {
+		d.TotalQuantity, _ = strconv.ParseFloat(m[1], 64)
+		d.recordMatch("total_quantity", reTotalQuantity, m[1])
+	}
+	if m := reItemCount.FindStringSubmatch(text); m != nil {
+		d.ItemCount, _ = strconv.Atoi(m[1])
+		d.recordMatch("item_count", reItemCount, m[1])
+	}
+
+	if d.ItemCount > 0 && len(d.LineItems) > 0 && d.ItemCount != len(d.LineItems) {
+		d.warn(SeverityWarning, WarnItemCountMismatch, "document states %d items but %d line items were parsed", d.ItemCount, len(d.LineItems))
+	}
+}
@@ -0,0 +1,103 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseItemRow(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		want LineItem
+	}{
+		{
+			name: "quantity after rate",
+			row:  "Wireless Mouse 500.00 2 1,000.00",
+			want: LineItem{Description: "Wireless Mouse", UnitPrice: "500.00", Quantity: "2", Amount: "1,000.00"},
+		},
+		{
+			name: "quantity column before rate",
+			row:  "Wireless Mouse   2   500.00   1,000.00",
+			want: LineItem{Description: "Wireless Mouse", UnitPrice: "500.00", Quantity: "2", Amount: "1,000.00"},
+		},
+		{
+			name: "quantity before rate in single-spaced text",
+			row:  "Wireless Mouse 2 500.00 1,000.00",
+			want: LineItem{Description: "Wireless Mouse", UnitPrice: "500.00", Quantity: "2", Amount: "1,000.00"},
+		},
+		{
+			name: "quantity before rate with HSN and tax",
+			row:  "USB Cable HSN 8544 3 150.00 18% 81.00 531.00",
+			want: LineItem{Description: "USB Cable", HSN: "8544", UnitPrice: "150.00", Quantity: "3", TaxRate: "18%", TaxAmount: "81.00", Amount: "531.00"},
+		},
+		{
+			name: "number ending the description is not a quantity",
+			row:  "iPhone 15 79,900.00 79,900.00",
+			want: LineItem{Description: "iPhone 15", UnitPrice: "79,900.00", Amount: "79,900.00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseItemRow(tt.row)
+			if !ok {
+				t.Fatalf("parseItemRow(%q) found no item", tt.row)
+			}
+			if got != tt.want {
+				t.Errorf("parseItemRow(%q)\n got %+v\nwant %+v", tt.row, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLineItemsWrappedDescriptions(t *testing.T) {
+	text := strings.Join([]string{
+		"TAX INVOICE",
+		"Sl Description HSN Qty Rate Amount",
+		"1 Stainless Steel Water Bottle HSN 7323 2 450.00 900.00",
+		"  Insulated, 750 ml, Pack of 12",
+		"  Colour: Blue / Model 2024",
+		"2 USB-C Cable HSN 8544 3 150.00 450.00",
+		"| (1.5 m, 60W) |",
+		"3 Notebook A5 1 120.00 120.00",
+		"Total 1,470.00",
+		"Thank you for your business",
+	}, "\n")
+
+	want := []LineItem{
+		{Description: "Stainless Steel Water Bottle Insulated, 750 ml, Pack of 12 Colour: Blue / Model 2024", HSN: "7323", Quantity: "2", UnitPrice: "450.00", Amount: "900.00"},
+		{Description: "USB-C Cable (1.5 m, 60W)", HSN: "8544", Quantity: "3", UnitPrice: "150.00", Amount: "450.00"},
+		{Description: "Notebook A5", Quantity: "1", UnitPrice: "120.00", Amount: "120.00"},
+	}
+	got := parseLineItems(text)
+	if len(got) != len(want) {
+		t.Fatalf("parseLineItems returned %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d\n got %+v\nwant %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestIsContinuationLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"  Insulated, 750 ml, Pack of 12", true},
+		{"Model 2024 / 128GB", true},
+		{"| (1.5 m, 60W) |", true},
+		{"Size 10.5", true},
+		{"2 USB-C Cable 150.00 450.00", false}, // a new numbered row
+		{"HSN 8544", false},
+		{"Freight charges 50.00", false},
+		{"   ", false},
+		{"| |", false},
+	}
+	for _, tt := range tests {
+		if got := isContinuationLine(tt.line); got != tt.want {
+			t.Errorf("isContinuationLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package extractor
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// logger receives the package's diagnostic output. It is slog.Default until
+// SetLogger is called.
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger routes the package's diagnostic output, such as the debug dump of
+// each extraction, to l. Invoice data is logged, so l should redact PII when
+// its output leaves the host.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx that routes the output of extractions run
+// with it to l instead of the SetLogger logger, so that it can carry
+// attributes of the caller such as a request ID.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// log returns the logger of ctx set with WithLogger, or else the logger set
+// with SetLogger, or slog.Default.
+func log(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,40 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of party reported in InvoiceDetails.BillingNameType.
+const (
+	NameCompany    = "company"
+	NameIndividual = "individual"
+)
+
+var (
+	// reCompanyName matches words that mark a business name.
+	reCompanyName = regexp.MustCompile(`(?i)\b(?:Ltd|Limited|Pvt|Private|LLP|Inc|Corp(?:oration)?|Co|Company|Enterprises?|Industries|Traders|Trading|Solutions|Technologies|Tech|Services|Systems|Agency|Agencies|Associates|Group|Holdings|Stores?|Mart|Exports?|Imports?|Sons|Bros|Brothers|Foundation|Trust|Hospital|School|College|University)\b\.?`)
+
+	// reHonorific matches the titles that precede personal names.
+	reHonorific = regexp.MustCompile(`(?i)^(?:Mr|Mrs|Ms|Miss|Dr|Shri|Sri|Smt|Kumari|Prof)\b\.?\s+`)
+
+	// rePersonName matches two to four alphabetic words, the usual shape of a personal name.
+	rePersonName = regexp.MustCompile(`^[A-Za-z][A-Za-z.']*(?:\s+[A-Za-z][A-Za-z.']*){1,3}$`)
+)
+
+// classifyName guesses whether name belongs to a company or an individual,
+// from business suffixes, honorifics and the shape of the name. It returns ""
+// when neither heuristic applies.
+func classifyName(name string) string {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return ""
+	case reCompanyName.MatchString(name):
+		return NameCompany
+	case reHonorific.MatchString(name), rePersonName.MatchString(name):
+		return NameIndividual
+	default:
+		return ""
+	}
+}
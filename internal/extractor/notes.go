@@ -0,0 +1,58 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// reNotesHeading matches a line opening a notes section, capturing any text
+	// on the same line after the heading.
+	reNotesHeading = regexp.MustCompile(`(?i)^\s*(?:Notes?|Remarks?|Terms(?:\s*(?:&|and)\s*Conditions)?|Special\s+Instructions)\s*[:\-]?\s*(.*)$`)
+
+	// reNotesBoundary matches a line that starts the next section of the invoice.
+	reNotesBoundary = regexp.MustCompile(`(?i)^\s*(?:(?:For\s+.*)?Authori[sz]ed\s+Signatory|Signature|Bank\s+Details|Declaration|(?:Grand\s+|Sub\s*)?Total\b|Amount\s+in\s+Words|Page\s+\d|This\s+is\s+a\s+computer)`)
+)
+
+// parseNotes collects the free text under each notes heading, up to a blank
+// line, a line ending in a colon or the start of another section, and joins the
+// sections with newlines. The result is cut to maxLen bytes with a warning.
+func (d *InvoiceDetails) parseNotes(text string, maxLen int) {
+	var sections []string
+	var current []string
+	inNotes := false
+	flush := func() {
+		if len(current) > 0 {
+			sections = append(sections, strings.Join(current, " "))
+		}
+		current, inNotes = nil, false
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if m := reNotesHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			inNotes = true
+			if rest := strings.Join(strings.Fields(m[1]), " "); rest != "" {
+				current = append(current, rest)
+			}
+			continue
+		}
+		if !inNotes {
+			continue
+		}
+		trimmed := strings.Join(strings.Fields(line), " ")
+		if trimmed == "" || strings.HasSuffix(trimmed, ":") || reNotesBoundary.MatchString(trimmed) {
+			flush()
+			continue
+		}
+		current = append(current, trimmed)
+	}
+	flush()
+
+	notes, cut := truncateText(strings.Join(sections, "\n"), maxLen)
+	if cut {
+		d.warn(SeverityInfo, WarnNotesTruncated, "notes exceeded %d bytes and were truncated", maxLen)
+	}
+	d.Notes = strings.TrimSpace(notes)
+	d.recordMatch("notes", reNotesHeading, d.Notes)
+}
@@ -0,0 +1,84 @@
+package extractor
+
+import "strings"
+
+// digitZeros lists the code point of the digit zero for each non-Latin numeral
+// system found on regional invoices. Each system's digits are contiguous from zero.
+var digitZeros = []rune{
+	0x0660, // Arabic-Indic
+	0x06F0, // Extended Arabic-Indic (Persian, Urdu)
+	0x0966, // Devanagari
+	0x09E6, // Bengali
+	0x0A66, // Gurmukhi
+	0x0AE6, // Gujarati
+	0x0B66, // Oriya
+	0x0BE6, // Tamil
+	0x0C66, // Telugu
+	0x0CE6, // Kannada
+	0x0D66, // Malayalam
+	0xFF10, // Fullwidth
+}
+
+// Separators that stand in for the ASCII comma and period in some locales.
+const (
+	arabicDecimalSeparator   = '\u066b'
+	arabicThousandsSeparator = '\u066c'
+)
+
+// isGroupSpace reports whether r is a space character used to group thousands.
+func isGroupSpace(r rune) bool {
+	switch r {
+	case '\u00a0', // no-break space
+		'\u2009', // thin space
+		'\u202f': // narrow no-break space
+		return true
+	}
+	return false
+}
+
+// asciiDigit maps r to its ASCII digit when it is a digit of a known numeral system.
+func asciiDigit(r rune) (rune, bool) {
+	if r >= '0' && r <= '9' {
+		return r, true
+	}
+	for _, zero := range digitZeros {
+		if r >= zero && r <= zero+9 {
+			return '0' + (r - zero), true
+		}
+	}
+	return r, false
+}
+
+// normalizeNumerals rewrites text so that the amount and date patterns, which
+// only know ASCII, can match it. Non-Latin digits become ASCII digits, Arabic
+// separators become "," and ".", and no-break or thin spaces between two digits
+// are treated as thousands separators and become ",".
+func normalizeNumerals(text string) string {
+	runes := []rune(text)
+	for i, r := range runes {
+		if d, ok := asciiDigit(r); ok {
+			runes[i] = d
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, r := range runes {
+		switch {
+		case r == arabicDecimalSeparator:
+			r = '.'
+		case r == arabicThousandsSeparator:
+			r = ','
+		case isGroupSpace(r) && betweenDigits(runes, i):
+			r = ','
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// betweenDigits reports whether runes[i] has an ASCII digit on both sides.
+func betweenDigits(runes []rune, i int) bool {
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	return i > 0 && i < len(runes)-1 && isDigit(runes[i-1]) && isDigit(runes[i+1])
+}
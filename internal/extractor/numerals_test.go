@@ -0,0 +1,40 @@
+package extractor
+
+import "testing"
+
+func TestNormalizeNumerals(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"devanagari digits", "कुल १,२३४.५०", "कुल 1,234.50"},
+		{"devanagari lakh grouping", "TOTAL ₹ १,२३,४५६.००", "TOTAL ₹ 1,23,456.00"},
+		{"arabic-indic digits and separators", "٢٬٣٤٥٫٠٠", "2,345.00"},
+		{"fullwidth digits", "１２３.４５", "123.45"},
+		{"no-break space separator", "1\u00a0234.00", "1,234.00"},
+		{"thin space separator", "12\u200934\u2009567.00", "12,34,567.00"},
+		{"narrow no-break space separator", "1\u202f234\u202f567.89", "1,234,567.89"},
+		{"devanagari with no-break space", "१\u00a0२३४.००", "1,234.00"},
+		{"no-break space after a word is kept", "TOTAL\u00a0500.00", "TOTAL\u00a0500.00"},
+		{"plain space is not a separator", "10.00 234.00", "10.00 234.00"},
+		{"ascii untouched", "Invoice INV-0042 dated 01/02/2024", "Invoice INV-0042 dated 01/02/2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeNumerals(tt.text); got != tt.want {
+				t.Errorf("normalizeNumerals(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// Normalized amounts must parse to the same value as their ASCII spelling.
+func TestNormalizeNumeralsParses(t *testing.T) {
+	for _, printed := range []string{"१,२३४.५०", "1\u00a0234.50", "1\u2009234.50", "١٬٢٣٤٫٥٠"} {
+		got, ok := parseAmount(normalizeNumerals(printed))
+		if !ok || got != 1234.5 {
+			t.Errorf("parseAmount(normalizeNumerals(%q)) = %v, %v, want 1234.5", printed, got, ok)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Text sources selectable with Options.TextSource.
+const (
+	// TextSourceAuto reads the text layer and falls back to OCR when it holds
+	// fewer than Config.OCRFallbackChars letters and digits.
+	TextSourceAuto = "auto"
+	// TextSourceText reads only the text layer.
+	TextSourceText = "text"
+	// TextSourceOCR skips the text layer and reads the pages through OCR.
+	TextSourceOCR = "ocr"
+)
+
+// sourceOCR is reported in InvoiceDetails.Source for text read through OCR.
+const sourceOCR = "ocr"
+
+// validTextSource reports whether source is a known text source; empty means auto.
+func validTextSource(source string) bool {
+	switch source {
+	case "", TextSourceAuto, TextSourceText, TextSourceOCR:
+		return true
+	}
+	return false
+}
+
+// textFromOCR makes OCR stand in for the text layouts of one part when
+// opts.TextSource asks for it: always for TextSourceOCR, and for
+// TextSourceAuto when the text layer is too thin to parse, as in a scan.
+// It reports whether the part's text now comes from OCR. A fallback that fails
+// leaves the text layer in place.
+func textFromOCR(ctx context.Context, pdf []byte, texts map[string]passText, opts Options) (bool, error) {
+	switch opts.TextSource {
+	case TextSourceText:
+		return false, nil
+	case TextSourceOCR:
+		pt, ok := texts["ocr"]
+		if !ok {
+			return false, nil
+		}
+		delete(texts, "ocr")
+		pt.engine = sourceOCR
+		texts["simple"], texts["columns"] = pt, pt
+		return true, nil
+	}
+
+	threshold := activeConfig().OCRFallbackChars
+	simple, ok := texts["simple"]
+	if threshold == 0 || !ok || alnumCount(simple.text) >= threshold {
+		return false, nil
+	}
+	pt, err := runPass(ctx, pdf, textPass{mode: "ocr"})
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, &StageError{Stage: StagePython + "/ocr", Err: fmt.Errorf("OCR fallback: %w", err)}
+		}
+		// Without OCR available, the thin text layer is still the best there is.
+		log(ctx).Warn("OCR fallback failed, parsing the text layer", "error", err)
+		return false, nil
+	}
+	pt.engine = sourceOCR
+	texts["simple"], texts["columns"] = pt, pt
+	return true, nil
+}
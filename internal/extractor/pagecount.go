@@ -0,0 +1,148 @@
+package extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// rePagesNode finds the page tree nodes, whose /Count is the number of
+	// pages beneath them; the root node counts the whole document.
+	rePagesNode = regexp.MustCompile(`/Type\s*/Pages\b`)
+	rePageCount = regexp.MustCompile(`/Count\s+(\d+)`)
+	// rePageLeaf finds the individual pages, for files without a usable tree.
+	rePageLeaf = regexp.MustCompile(`/Type\s*/Page\b`)
+	// reObjectStream finds the compressed object streams newer PDFs keep the
+	// page tree in.
+	reObjectStream = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	reStreamStart  = regexp.MustCompile(`stream\r?\n`)
+)
+
+// maxInflatedObjects caps the bytes inflated from object streams while
+// counting pages, so a compression bomb cannot exhaust memory.
+const maxInflatedObjects = 32 << 20
+
+// maxDictScan is how far, in bytes, the dictionary around a page tree node
+// is searched for its /Count.
+const maxDictScan = 64 << 10
+
+// PageCount returns the number of pages of pdf, read from its page tree
+// without rendering or fully parsing the file. The second return value is
+// false when no page tree or pages could be found, as in some damaged files.
+func PageCount(pdf []byte) (int, bool) {
+	objects := [][]byte{pdf}
+	budget := maxInflatedObjects
+	for _, loc := range reObjectStream.FindAllIndex(pdf, -1) {
+		if budget <= 0 {
+			break
+		}
+		inflated := inflateStream(pdf[loc[1]:], budget)
+		budget -= len(inflated)
+		objects = append(objects, inflated)
+	}
+
+	// Superseded trees left by incremental updates are rare, and counting
+	// them only errs on the side of more pages.
+	count, found := 0, false
+	for _, data := range objects {
+		for _, loc := range rePagesNode.FindAllIndex(data, -1) {
+			m := rePageCount.FindSubmatch(enclosingDict(data, loc[0]))
+			if m == nil {
+				continue
+			}
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n > count {
+				count, found = n, true
+			}
+		}
+	}
+	if found {
+		return count, true
+	}
+
+	for _, data := range objects {
+		count += len(rePageLeaf.FindAllIndex(data, -1))
+	}
+	return count, count > 0
+}
+
+// inflateStream inflates the first stream in data, which follows the
+// dictionary of an object stream, reading at most limit bytes. It returns nil
+// when the stream is not Flate-compressed or cannot be inflated.
+func inflateStream(data []byte, limit int) []byte {
+	loc := reStreamStart.FindIndex(data)
+	if loc == nil || loc[0] > maxDictScan {
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data[loc[1]:]))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	// A stream truncated by a damaged file still yields what was inflated.
+	inflated, _ := io.ReadAll(io.LimitReader(r, int64(limit)))
+	return inflated
+}
+
+// enclosingDict returns the dictionary, delimited by "<<" and ">>", that
+// contains offset pos of data, or nil when there is none within maxDictScan.
+func enclosingDict(data []byte, pos int) []byte {
+	start, depth := -1, 0
+	for i := pos - 1; i > 0 && pos-i < maxDictScan; i-- {
+		switch {
+		case data[i-1] == '>' && data[i] == '>':
+			depth++
+			i--
+		case data[i-1] == '<' && data[i] == '<':
+			if depth == 0 {
+				start = i - 1
+			}
+			depth--
+			i--
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	depth = 0
+	for i := start; i+1 < len(data) && i-start < maxDictScan; i++ {
+		switch {
+		case data[i] == '<' && data[i+1] == '<':
+			depth++
+			i++
+		case data[i] == '>' && data[i+1] == '>':
+			if depth--; depth == 0 {
+				return data[start : i+2]
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// checkPageCount fails with ErrTooManyPages when the parts together have more
+// than Config.MaxPages pages. Parts whose pages cannot be counted are let
+// through for the text passes to read or reject.
+func checkPageCount(pdfs [][]byte) error {
+	limit := activeConfig().MaxPages
+	if limit == 0 {
+		return nil
+	}
+	total := 0
+	for _, pdf := range pdfs {
+		if n, ok := PageCount(pdf); ok {
+			total += n
+		}
+	}
+	if total > limit {
+		return fmt.Errorf("%w: %d pages, at most %d are accepted", ErrTooManyPages, total, limit)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// objectStream returns a PDF object stream holding objects, Flate-compressed.
+func objectStream(objects string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(objects))
+	w.Close()
+	return fmt.Sprintf("5 0 obj\n<< /Type /ObjStm /N 2 /First 10 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n", buf.Len(), buf.String())
+}
+
+func TestPageCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		pdf    string
+		want   int
+		wantOK bool
+	}{
+		{
+			name:   "flat page tree",
+			pdf:    "%PDF-1.4\n2 0 obj\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>\nendobj\n3 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n4 0 obj\n<< /Type /Page /Parent 2 0 R >>\nendobj\n",
+			want:   2,
+			wantOK: true,
+		},
+		{
+			name:   "nested page tree counts the root",
+			pdf:    "%PDF-1.4\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 5 >>\n<< /Type /Pages /Parent 2 0 R /Count 3 >>\n<< /Type /Pages /Parent 2 0 R /Count 2 >>\n",
+			want:   5,
+			wantOK: true,
+		},
+		{
+			name:   "count before type and nested dictionaries",
+			pdf:    "%PDF-1.7\n<< /Count 7 /Resources << /Font << /F1 9 0 R >> >> /Type /Pages >>\n",
+			want:   7,
+			wantOK: true,
+		},
+		{
+			name:   "page tree in a compressed object stream",
+			pdf:    "%PDF-1.5\n" + objectStream("2 0 3 60\n<< /Type /Pages /Kids [3 0 R] /Count 12 >>\n<< /Type /Page /Parent 2 0 R >>\n"),
+			want:   12,
+			wantOK: true,
+		},
+		{
+			name:   "pages without a tree",
+			pdf:    "%PDF-1.4\n" + strings.Repeat("<< /Type /Page >>\n", 3),
+			want:   3,
+			wantOK: true,
+		},
+		{
+			name: "no pages",
+			pdf:  "%PDF-1.4\n%%EOF\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PageCount([]byte(tt.pdf))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("PageCount = %d, %t, want %d, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckPageCount(t *testing.T) {
+	limit := activeConfig().MaxPages
+	if limit == 0 {
+		t.Skip("page limit disabled by default configuration")
+	}
+	pdf := func(pages int) []byte {
+		return []byte(fmt.Sprintf("%%PDF-1.4\n<< /Type /Pages /Count %d >>\n", pages))
+	}
+
+	if err := checkPageCount([][]byte{pdf(limit)}); err != nil {
+		t.Errorf("%d pages: got %v, want no error", limit, err)
+	}
+	if err := checkPageCount([][]byte{pdf(limit + 1)}); !errors.Is(err, ErrTooManyPages) {
+		t.Errorf("%d pages: got %v, want ErrTooManyPages", limit+1, err)
+	}
+	// The limit applies to the parts of a split invoice together.
+	if err := checkPageCount([][]byte{pdf(limit), pdf(1)}); !errors.Is(err, ErrTooManyPages) {
+		t.Errorf("parts of %d and 1 pages: got %v, want ErrTooManyPages", limit, err)
+	}
+}
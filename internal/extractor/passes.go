@@ -0,0 +1,123 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// textPass is one run of the Python script over the PDF.
+type textPass struct {
+	mode  string
+	pages []int
+}
+
+// passesFor lists the text passes an extraction with opts needs.
+func passesFor(opts Options) []textPass {
+	if opts.TextSource == TextSourceOCR {
+		// OCR replaces the text layouts, covering the requested pages if any.
+		return []textPass{{mode: "ocr", pages: opts.OCRPages}}
+	}
+	passes := []textPass{{mode: "simple"}, {mode: "columns"}}
+	if len(opts.OCRPages) > 0 {
+		passes = append(passes, textPass{mode: "ocr", pages: opts.OCRPages})
+	}
+	return passes
+}
+
+// passText is the text a pass produced and the engine that produced it.
+type passText struct {
+	text   string
+	engine string
+}
+
+// runPass runs one pass. The 'simple' pass tries the configured engines in order
+// until one yields usable text; if none does, the richest text wins. An engine
+// that fails is skipped unless it is the last one left. The other modes are only
+// supported by the default engine.
+func runPass(ctx context.Context, pdf []byte, p textPass) (passText, error) {
+	if p.mode != "simple" {
+		text, err := extractTextWithPython(ctx, bytes.NewReader(pdf), p.mode, p.pages, "")
+		return passText{text: text, engine: defaultEngine}, err
+	}
+
+	engines := activeConfig().Engines
+	var best passText
+	var lastErr error
+	for _, engine := range engines {
+		text, err := extractTextWithPython(ctx, bytes.NewReader(pdf), p.mode, p.pages, engine)
+		if err != nil {
+			if ctx.Err() != nil {
+				return passText{}, err
+			}
+			lastErr = err
+			continue
+		}
+		if usableText(text) {
+			return passText{text: text, engine: engine}, nil
+		}
+		if best.engine == "" || alnumCount(text) > alnumCount(best.text) {
+			best = passText{text: text, engine: engine}
+		}
+	}
+	if best.engine == "" {
+		return passText{}, lastErr
+	}
+	return best, nil
+}
+
+// runPasses runs the passes one after another, keyed by mode in the result.
+// Running them in sequence keeps each extraction to one Python process at a time.
+func runPasses(ctx context.Context, pdf []byte, passes []textPass) (map[string]passText, error) {
+	texts := make(map[string]passText, len(passes))
+	for _, p := range passes {
+		text, err := runPass(ctx, pdf, p)
+		if err != nil {
+			return nil, &StageError{Stage: StagePython + "/" + p.mode, Err: err}
+		}
+		texts[p.mode] = text
+	}
+	return texts, nil
+}
+
+// runPassesWithin runs the passes concurrently and returns the texts of those that
+// finished within d. Passes still running at the deadline are killed. It fails only
+// when a pass errors or when none finished in time.
+func runPassesWithin(ctx context.Context, pdf []byte, passes []textPass, d time.Duration) (map[string]passText, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Kills the subprocesses of any pass still running.
+
+	type result struct {
+		mode string
+		text passText
+		err  error
+	}
+	results := make(chan result, len(passes))
+	for _, p := range passes {
+		go func() {
+			text, err := runPass(ctx, pdf, p)
+			results <- result{mode: p.mode, text: text, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	texts := make(map[string]passText, len(passes))
+	for range passes {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				return nil, &StageError{Stage: StagePython + "/" + res.mode, Err: res.err}
+			}
+			texts[res.mode] = res.text
+		case <-timer.C:
+			if len(texts) == 0 {
+				return nil, &StageError{Stage: StagePython, Err: fmt.Errorf("%w: no text extraction finished before the soft deadline", ErrExtractionTimeout)}
+			}
+			return texts, nil
+		}
+	}
+	return texts, nil
+}
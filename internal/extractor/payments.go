@@ -0,0 +1,59 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+)
+
+var (
+	reAmountPaid = regexp.MustCompile(`(?im)^.*?\b(?:Amount\s+Paid|Paid\s+Amount|Advance\s+Paid|Payment\s+Received|Less\s*:?\s*Advance)\b\s*:?(.*)$`)
+	reBalanceDue = regexp.MustCompile(`(?im)^.*?\b(?:Balance\s+Due|Amount\s+Due|Balance\s+Payable|Balance\s+Amount|Outstanding\s+Amount)\b\s*:?(.*)$`)
+)
+
+// parsePayments extracts the amount already paid and the balance still due.
+// When only one of them is printed, the other is derived from the total, which
+// covers invoices printing "Balance Due: 0.00" once paid in full and those that
+// only state the amount due while unpaid. When both are printed, it records a
+// mismatch if they don't add up to the total. Both stay nil when neither is
+// printed.
+func (d *InvoiceDetails) parsePayments(text string) {
+	paid, paidOK := lastLabelledAmount(reAmountPaid, text)
+	due, dueOK := lastLabelledAmount(reBalanceDue, text)
+	if !paidOK && !dueOK {
+		return
+	}
+
+	precision := activeConfig().AmountPrecision
+	total := math.Abs(d.TotalAmountValue)
+	switch {
+	case paidOK && dueOK:
+		if d.TotalAmount != "" && math.Abs(total-paid-due) > 0.01 {
+			d.mismatch(CheckPayments, WarnPaymentMismatch, "amount paid %.2f and balance due %.2f do not add up to the total %.2f", paid, due, total)
+		}
+	case paidOK && d.TotalAmount != "":
+		due = roundTo(total-paid, precision)
+	case dueOK && d.TotalAmount != "":
+		paid = roundTo(total-due, precision)
+	}
+
+	if paidOK || d.TotalAmount != "" {
+		d.AmountPaid = &paid
+	}
+	if dueOK || d.TotalAmount != "" {
+		d.BalanceDue = &due
+	}
+}
+
+// lastLabelledAmount returns the last amount on the last line matched by re that
+// carries one, as an absolute value rounded to the configured precision.
+func lastLabelledAmount(re *regexp.Regexp, text string) (float64, bool) {
+	matches := re.FindAllStringSubmatch(text, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		if amounts := reAmount.FindAllString(matches[i][1], -1); len(amounts) > 0 {
+			if v, ok := parseAmount(amounts[len(amounts)-1]); ok {
+				return roundTo(math.Abs(v), activeConfig().AmountPrecision), true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,12 @@
+package extractor
+
+import "bytes"
+
+// pdfMagic is the header every PDF file starts with.
+var pdfMagic = []byte("%PDF-")
+
+// IsPDF reports whether data starts with the PDF header, so other files can be
+// rejected before any Python process is started for them.
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, pdfMagic)
+}
@@ -0,0 +1,51 @@
+package extractor
+
+import "strings"
+
+// normalizeE164 converts a printed phone number into E.164 form ("+919876543210").
+// Numbers without an international prefix are assumed to belong to countryCode,
+// after dropping any national trunk prefix ("0"). The second return value is
+// false when the number cannot be a valid E.164 number; it is then left alone
+// rather than forced into shape.
+func normalizeE164(raw, countryCode string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+
+	var b strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case international:
+		// Already carries its country code.
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case len(digits) > 10 && strings.HasPrefix(digits, countryCode):
+		// Country code printed without the "+".
+	default:
+		digits = countryCode + strings.TrimLeft(digits, "0")
+	}
+
+	// E.164 allows at most 15 digits; anything under 8 is too short to be dialable.
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", false
+	}
+	return "+" + digits, true
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package extractor
+
+import "regexp"
+
+var (
+	// rePlaceOfSupply captures the rest of the line after a "Place of Supply" label,
+	// or the next line when the label stands alone.
+	rePlaceOfSupply = regexp.MustCompile(`(?i)\bPlace\s+of\s+Supply\b\s*[:\-]?\s*([^\n]*)`)
+
+	// rePlaceOfSupplyCode matches a state code printed with the place of supply:
+	// leading ("29-Karnataka"), in brackets ("Karnataka (29)") or labelled
+	// ("State Code: 29").
+	rePlaceOfSupplyCode = regexp.MustCompile(`(?i)^(\d{2})\b|\(\s*(\d{2})\s*\)|\bCode\s*[:\-]?\s*(\d{2})\b`)
+)
+
+// PlaceOfSupply is the state a GST invoice declares as its place of supply,
+// which decides between intrastate (CGST and SGST) and interstate (IGST) tax.
+type PlaceOfSupply struct {
+	// Name is the state's name as listed by the GST portal, and Code its
+	// two-digit GST state code. Either is derived from the other when the
+	// invoice prints only one.
+	Name string `json:"name"`
+	Code string `json:"code"`
+
+	// DiffersFromClient compares Code with the client's state, taken from the
+	// state code or else the client GSTIN. It is nil when the client's state
+	// is unknown.
+	DiffersFromClient *bool `json:"differs_from_client,omitempty"`
+}
+
+// parsePlaceOfSupply reads the labelled place of supply from text. A printed
+// code that contradicts the printed name is kept, with a warning.
+func (d *InvoiceDetails) parsePlaceOfSupply(text string) {
+	m := rePlaceOfSupply.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	value := m[1]
+
+	var code string
+	if c := rePlaceOfSupplyCode.FindStringSubmatch(value); c != nil {
+		for _, group := range c[1:] {
+			if validStateCode(group) {
+				code = group
+			}
+		}
+	}
+	name := findStateName(value)
+	if code == "" && name == "" {
+		d.warn(SeverityWarning, WarnUnknownState, "place of supply %q is not a known state", cleanMatch(m, 1))
+		return
+	}
+
+	pos := &PlaceOfSupply{Name: name, Code: code}
+	switch {
+	case code == "":
+		pos.Code, _ = stateCodeByName(name)
+	case name == "":
+		pos.Name = gstStates[code]
+	default:
+		if named, _ := stateCodeByName(name); named != code {
+			d.warn(SeverityError, WarnPlaceOfSupply, "place of supply code %s does not match the state %s", code, name)
+			d.ambiguous("place of supply code %s does not match the state %s", code, name)
+		}
+	}
+	d.PlaceOfSupply = pos
+	d.recordMatch("place_of_supply", rePlaceOfSupply, m[0])
+}
+
+// comparePlaceOfSupply sets PlaceOfSupply.DiffersFromClient once the client's
+// state code or GSTIN is known.
+func (d *InvoiceDetails) comparePlaceOfSupply() {
+	if d.PlaceOfSupply == nil {
+		return
+	}
+	client := d.StateCode
+	if client == "" && len(d.GSTNOClient) >= 2 && validStateCode(d.GSTNOClient[:2]) {
+		client = d.GSTNOClient[:2]
+	}
+	if client == "" {
+		return
+	}
+	differs := client != d.PlaceOfSupply.Code
+	d.PlaceOfSupply.DiffersFromClient = &differs
+}
@@ -0,0 +1,226 @@
+package extractor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// errWorkerDied reports that a pooled Python worker exited or broke its pipes
+// while serving a request.
+var errWorkerDied = errors.New("python worker died")
+
+// workerRequest and workerReply are the line-delimited JSON messages exchanged
+// with the script's --serve mode.
+type workerRequest struct {
+	PDFPath string `json:"pdf_path"`
+	Mode    string `json:"mode"`
+	Pages   []int  `json:"pages,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+}
+
+type workerReply struct {
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+// pythonWorker is a long-lived `pdf_text_extractor.py --serve` process.
+type pythonWorker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// startWorker launches a worker process.
+func startWorker() (*pythonWorker, error) {
+	cfg := activeConfig()
+	cmd := exec.Command(cfg.PythonPath, cfg.scriptPath, "--serve")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: failed to start python worker: %w", ErrPythonUnavailable, err)
+	}
+	return &pythonWorker{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// do sends one request and reads its reply. A broken pipe or an early exit is
+// reported as errWorkerDied; the worker must not be reused after any error
+// other than one reported by the script itself.
+func (w *pythonWorker) do(req workerRequest) (string, error) {
+	line, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.stdin.Write(append(line, '\n')); err != nil {
+		return "", fmt.Errorf("%w: %v", errWorkerDied, err)
+	}
+	raw, err := w.stdout.ReadBytes('\n')
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errWorkerDied, err)
+	}
+	var reply workerReply
+	if err := json.Unmarshal(raw, &reply); err != nil {
+		return "", fmt.Errorf("%w: unreadable reply: %v", errWorkerDied, err)
+	}
+	if reply.Error != "" {
+		return "", &scriptError{msg: reply.Error}
+	}
+	return reply.Text, nil
+}
+
+// stop kills the worker and reaps it.
+func (w *pythonWorker) stop() {
+	w.stdin.Close()
+	w.cmd.Process.Kill()
+	w.cmd.Wait()
+}
+
+// scriptError is an error the script reported for a request, leaving the
+// worker healthy.
+type scriptError struct{ msg string }
+
+func (e *scriptError) Error() string { return e.msg }
+
+// workerPool hands out up to size workers at a time, keeping idle ones for
+// reuse. Workers are started on demand, so one that died is replaced by the
+// next request that needs it.
+type workerPool struct {
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*pythonWorker
+	closed bool
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{slots: make(chan struct{}, size)}
+}
+
+// extract runs one request on a pooled worker. Cancelling ctx kills the
+// worker serving it. A reused worker found dead is replaced and the request
+// retried once.
+func (p *workerPool) extract(ctx context.Context, req workerRequest) (string, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	for attempt := 0; ; attempt++ {
+		w, reused, err := p.take()
+		if err != nil {
+			return "", err
+		}
+
+		type result struct {
+			text string
+			err  error
+		}
+		done := make(chan result, 1)
+		go func() {
+			text, err := w.do(req)
+			done <- result{text, err}
+		}()
+
+		var res result
+		select {
+		case res = <-done:
+		case <-ctx.Done():
+			w.stop()
+			<-done
+			return "", ctx.Err()
+		}
+
+		var scriptErr *scriptError
+		if res.err == nil || errors.As(res.err, &scriptErr) {
+			p.put(w)
+			return res.text, res.err
+		}
+		w.stop()
+		if !reused || attempt > 0 || !errors.Is(res.err, errWorkerDied) {
+			return "", res.err
+		}
+	}
+}
+
+// take returns an idle worker, reporting it as reused, or starts a new one.
+func (p *workerPool) take() (w *pythonWorker, reused bool, err error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		w = p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return w, true, nil
+	}
+	p.mu.Unlock()
+	w, err = startWorker()
+	return w, false, err
+}
+
+// put returns a healthy worker for reuse, or stops it once the pool is closed.
+func (p *workerPool) put(w *pythonWorker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		go w.stop()
+		return
+	}
+	p.idle = append(p.idle, w)
+}
+
+// close stops the idle workers; busy ones are stopped as they are returned.
+func (p *workerPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, w := range p.idle {
+		go w.stop()
+	}
+	p.idle = nil
+}
+
+// poolSettings are the settings a worker pool is built for.
+type poolSettings struct {
+	size           int
+	python, script string
+}
+
+var (
+	poolMu sync.Mutex
+	// pool is the worker pool for poolConfig, nil when pooling is off.
+	pool       *workerPool
+	poolConfig poolSettings
+)
+
+// pythonPool returns the worker pool sized by Config.PythonWorkers, replacing
+// the pool when the size or the Python paths changed, or nil when workers are
+// disabled.
+func pythonPool() *workerPool {
+	cfg := activeConfig()
+	want := poolSettings{cfg.PythonWorkers, cfg.PythonPath, cfg.scriptPath}
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if want != poolConfig {
+		if pool != nil {
+			pool.close()
+			pool = nil
+		}
+		if want.size > 0 {
+			pool = newWorkerPool(want.size)
+		}
+		poolConfig = want
+	}
+	return pool
+}
@@ -0,0 +1,69 @@
+package extractor
+
+// documentField is a field read from the document, by JSON name, and whether
+// a value was found for it.
+type documentField struct {
+	name  string
+	found bool
+}
+
+// documentFields lists the fields of d that are read from the document,
+// leaving out values the extractor fills in on its own such as the document
+// type, the source hash or derived amounts.
+func (d *InvoiceDetails) documentFields() []documentField {
+	fields := make([]documentField, 0, 23)
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"invoice_number", d.InvoiceNumber},
+		{"invoice_date", d.InvoiceDate},
+		{"order_number", d.OrderNumber},
+		{"order_date", d.OrderDate},
+		{"billing_name", d.BillingName},
+		{"billing_address", d.BillingAddress},
+		{"state_code", d.StateCode},
+		{"gst_no_client", d.GSTNOClient},
+		{"tax_amount", d.TaxAmount},
+		{"total_amount", d.TotalAmount},
+		{"hsn", d.HSN},
+		{"asn", d.ASN},
+		{"challan_number", d.ChallanNumber},
+		{"reference_number", d.ReferenceNumber},
+		{"cin", d.CIN},
+		{"contact_phone", d.ContactPhone},
+		{"contact_email", d.ContactEmail},
+		{"billing_phone", d.BillingPhone},
+		{"billing_email", d.BillingEmail},
+		{"upi_id", d.UPIID},
+		{"notes", d.Notes},
+	} {
+		fields = append(fields, documentField{f.name, f.value != ""})
+	}
+	return append(fields,
+		documentField{"place_of_supply", d.PlaceOfSupply != nil},
+		documentField{"line_items", len(d.LineItems) > 0},
+	)
+}
+
+// PopulatedFields counts the fields of d that were read from the document.
+func (d *InvoiceDetails) PopulatedFields() int {
+	n := 0
+	for _, f := range d.documentFields() {
+		if f.found {
+			n++
+		}
+	}
+	return n
+}
+
+// missingFields returns the JSON names of the document fields left empty.
+func (d *InvoiceDetails) missingFields() []string {
+	missing := []string{}
+	for _, f := range d.documentFields() {
+		if !f.found {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
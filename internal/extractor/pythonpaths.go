@@ -0,0 +1,37 @@
+package extractor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ResolvePythonPaths checks that the interpreter and script named by cfg exist
+// and rewrites their paths as absolute ones, so extraction no longer depends
+// on the working directory. An interpreter given as a bare name, such as
+// "python3", is looked up in PATH.
+func ResolvePythonPaths(cfg *Config) error {
+	resolved, err := exec.LookPath(filepath.FromSlash(cfg.PythonPath))
+	if err != nil {
+		return fmt.Errorf("python interpreter %q not found: %w", cfg.PythonPath, err)
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return fmt.Errorf("python interpreter %q: %w", cfg.PythonPath, err)
+	}
+	cfg.PythonPath = resolved
+
+	script, err := filepath.Abs(filepath.FromSlash(cfg.ScriptPath))
+	if err != nil {
+		return fmt.Errorf("script %q: %w", cfg.ScriptPath, err)
+	}
+	info, err := os.Stat(script)
+	if err != nil {
+		return fmt.Errorf("script %q not found: %w", cfg.ScriptPath, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("script %q is a directory", cfg.ScriptPath)
+	}
+	cfg.ScriptPath = script
+	return nil
+}
@@ -0,0 +1,151 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// defaultFieldPatterns are the patterns each labelled field is read with when
+// no rule overrides it, tried in order until one matches.
+var defaultFieldPatterns = map[string][]*regexp.Regexp{
+	"invoice_number":   {reInvoiceNumber},
+	"invoice_date":     {reInvoiceDate},
+	"order_number":     {reOrderNo},
+	"order_date":       {reOrderDate},
+	"state_code":       {reStateCode},
+	"hsn":              {reHSN},
+	"challan_number":   {reChallan, reDeliveryNote},
+	"reference_number": {reReferenceNo},
+}
+
+// RuleFields lists the JSON names of the fields FieldRules may apply to.
+var RuleFields = func() []string {
+	fields := make([]string, 0, len(defaultFieldPatterns))
+	for field := range defaultFieldPatterns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}()
+
+// Rules is the content of a rules file, see LoadRules.
+type Rules struct {
+	// Fields is for Config.FieldRules, and CustomFields for Config.CustomFields.
+	Fields       map[string][]string
+	CustomFields map[string]string
+}
+
+// LoadRules reads field rules from a JSON file mapping each field to the
+// regular expressions tried, in order, to read it. The optional
+// "custom_fields" key maps custom field names to their pattern:
+//
+//	{
+//	  "invoice_number": ["(?i)Bill\\s*No\\.?\\s*[:\\-]?\\s*(\\S+)", "(?i)Invoice\\s*Number\\s*[:\\-]?\\s*(\\S+)"],
+//	  "custom_fields": {"due_date": "(?i)Due\\s*Date\\s*[:\\-]?\\s*(\\S+)"}
+//	}
+//
+// The rules are validated as Configure would validate them.
+func LoadRules(path string) (Rules, error) {
+	var rules Rules
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("reading rules: %w", err)
+	}
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return rules, fmt.Errorf("parsing rules %s: %w", path, err)
+	}
+	if custom, ok := file["custom_fields"]; ok {
+		delete(file, "custom_fields")
+		if err := json.Unmarshal(custom, &rules.CustomFields); err != nil {
+			return rules, fmt.Errorf("parsing rules %s: custom_fields: %w", path, err)
+		}
+		if err := ValidateCustomFields(rules.CustomFields); err != nil {
+			return rules, fmt.Errorf("rules %s: %w", path, err)
+		}
+	}
+	rules.Fields = make(map[string][]string, len(file))
+	for field, patterns := range file {
+		var list []string
+		if err := json.Unmarshal(patterns, &list); err != nil {
+			return rules, fmt.Errorf("parsing rules %s: %s: %w", path, field, err)
+		}
+		rules.Fields[field] = list
+	}
+	if _, err := compileFieldRules(rules.Fields); err != nil {
+		return rules, fmt.Errorf("rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// compileFieldRules compiles the patterns of each field rule. Every pattern
+// must capture the value in its first group.
+func compileFieldRules(rules map[string][]string) (map[string][]*regexp.Regexp, error) {
+	compiled := make(map[string][]*regexp.Regexp, len(rules))
+	for field, patterns := range rules {
+		if !slices.Contains(RuleFields, field) {
+			return nil, fmt.Errorf("rule for unknown field %q; valid fields are %s", field, strings.Join(RuleFields, ", "))
+		}
+		if len(patterns) == 0 {
+			return nil, fmt.Errorf("rule for %s has no patterns", field)
+		}
+		for i, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule for %s, pattern %d: %w", field, i+1, err)
+			}
+			if re.NumSubexp() < 1 {
+				return nil, fmt.Errorf("rule for %s, pattern %d %q has no capture group for the value", field, i+1, pattern)
+			}
+			compiled[field] = append(compiled[field], re)
+		}
+	}
+	return compiled, nil
+}
+
+// fieldPatterns returns the patterns field is read with: its configured rule,
+// or the built-in patterns when it has none.
+func fieldPatterns(field string) []*regexp.Regexp {
+	if patterns, ok := activeConfig().fieldRules[field]; ok {
+		return patterns
+	}
+	return defaultFieldPatterns[field]
+}
+
+// matchField reads field with each of its patterns in turn, cross-checking the
+// layouts as matchAcrossModes does, until one of them finds a value.
+func (d *InvoiceDetails) matchField(field string, dst *string, primary, secondary string) {
+	for _, re := range fieldPatterns(field) {
+		d.matchAcrossModes(field, dst, re, primary, secondary)
+		if *dst != "" {
+			return
+		}
+	}
+}
+
+// fieldRulePatterns pairs every pattern of the rule fields with its destination
+// in d, for the combined-text retry.
+func (d *InvoiceDetails) fieldRulePatterns() []fieldPattern {
+	dsts := map[string]*string{
+		"invoice_number":   &d.InvoiceNumber,
+		"invoice_date":     &d.InvoiceDate,
+		"order_number":     &d.OrderNumber,
+		"order_date":       &d.OrderDate,
+		"state_code":       &d.StateCode,
+		"hsn":              &d.HSN,
+		"challan_number":   &d.ChallanNumber,
+		"reference_number": &d.ReferenceNumber,
+	}
+	var fields []fieldPattern
+	for _, field := range RuleFields {
+		for _, re := range fieldPatterns(field) {
+			fields = append(fields, fieldPattern{field, dsts[field], re})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,53 @@
+package extractor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scriptModes are the only values ever passed to the script's --mode flag.
+var scriptModes = map[string]bool{
+	"simple":  true,
+	"columns": true,
+	"ocr":     true,
+}
+
+const (
+	// maxScriptPages caps how many pages may be named in a single --pages flag.
+	maxScriptPages = 50
+	// maxScriptPage is the highest page number forwarded to the script.
+	maxScriptPage = 10000
+)
+
+// scriptArgs builds the flags for the text extraction script. Every value is
+// checked against an allowlist or a strict numeric range first, so nothing a
+// request controls reaches the command line unchecked.
+func scriptArgs(mode string, pages []int, engine string) ([]string, error) {
+	if !scriptModes[mode] {
+		return nil, fmt.Errorf("unsupported extraction mode %q", mode)
+	}
+	args := []string{"--mode=" + mode}
+
+	if len(pages) > maxScriptPages {
+		return nil, fmt.Errorf("at most %d pages may be extracted at once", maxScriptPages)
+	}
+	if len(pages) > 0 {
+		numbers := make([]string, len(pages))
+		for i, page := range pages {
+			if page < 1 || page > maxScriptPage {
+				return nil, fmt.Errorf("page %d is out of range 1-%d", page, maxScriptPage)
+			}
+			numbers[i] = strconv.Itoa(page)
+		}
+		args = append(args, "--pages="+strings.Join(numbers, ","))
+	}
+
+	if engine != "" {
+		if err := validateEngines([]string{engine}); err != nil {
+			return nil, err
+		}
+		args = append(args, "--engine="+engine)
+	}
+	return args, nil
+}
@@ -0,0 +1,35 @@
+package extractor
+
+import "errors"
+
+// Stages an extraction can fail in, as reported by FailureStage. Python stages
+// are suffixed with the text mode, e.g. "python/simple".
+const (
+	StageRead   = "read"
+	StagePython = "python"
+	StageParse  = "parse"
+)
+
+// StageError is an extraction failure together with the stage it happened in.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Stage + ": " + e.Err.Error() }
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// FailureStage returns the stage an extraction error happened in, or "" when
+// it is not known. A strict-mode *AmbiguityError is a parse failure.
+func FailureStage(err error) string {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	var ambiguity *AmbiguityError
+	if errors.As(err, &ambiguity) {
+		return StageParse
+	}
+	return ""
+}
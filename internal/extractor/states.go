@@ -0,0 +1,123 @@
+package extractor
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// gstStates maps the GST state codes to the state or union territory names
+// used by the GST portal.
+var gstStates = map[string]string{
+	"01": "Jammu and Kashmir",
+	"02": "Himachal Pradesh",
+	"03": "Punjab",
+	"04": "Chandigarh",
+	"05": "Uttarakhand",
+	"06": "Haryana",
+	"07": "Delhi",
+	"08": "Rajasthan",
+	"09": "Uttar Pradesh",
+	"10": "Bihar",
+	"11": "Sikkim",
+	"12": "Arunachal Pradesh",
+	"13": "Nagaland",
+	"14": "Manipur",
+	"15": "Mizoram",
+	"16": "Tripura",
+	"17": "Meghalaya",
+	"18": "Assam",
+	"19": "West Bengal",
+	"20": "Jharkhand",
+	"21": "Odisha",
+	"22": "Chhattisgarh",
+	"23": "Madhya Pradesh",
+	"24": "Gujarat",
+	"25": "Daman and Diu",
+	"26": "Dadra and Nagar Haveli and Daman and Diu",
+	"27": "Maharashtra",
+	"28": "Andhra Pradesh (Old)",
+	"29": "Karnataka",
+	"30": "Goa",
+	"31": "Lakshadweep",
+	"32": "Kerala",
+	"33": "Tamil Nadu",
+	"34": "Puducherry",
+	"35": "Andaman and Nicobar Islands",
+	"36": "Telangana",
+	"37": "Andhra Pradesh",
+	"38": "Ladakh",
+	"97": "Other Territory",
+	"99": "Centre Jurisdiction",
+}
+
+// stateAliases maps former and informal state names to their GST code.
+var stateAliases = map[string]string{
+	"orissa":                 "21",
+	"pondicherry":            "34",
+	"uttaranchal":            "05",
+	"new delhi":              "07",
+	"nct of delhi":           "07",
+	"dadra and nagar haveli": "26",
+}
+
+// stateNames lists every known state name, normalized, longest first so that
+// "Dadra and Nagar Haveli and Daman and Diu" is not read as "Daman and Diu".
+var stateNames = func() []string {
+	var names []string
+	for code, name := range gstStates {
+		if code != "28" {
+			names = append(names, normalizeStateName(name))
+		}
+	}
+	for alias := range stateAliases {
+		names = append(names, alias)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	return names
+}()
+
+var reNonLetters = regexp.MustCompile(`[^a-z]+`)
+
+// normalizeStateName lowercases name, spells out "&" and reduces everything
+// but letters to single spaces, so printed variants compare equal.
+func normalizeStateName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+	return strings.TrimSpace(reNonLetters.ReplaceAllString(name, " "))
+}
+
+// stateCodeByName returns the GST code of the state named name.
+func stateCodeByName(name string) (string, bool) {
+	name = normalizeStateName(name)
+	if code, ok := stateAliases[name]; ok {
+		return code, true
+	}
+	for code, state := range gstStates {
+		if code != "28" && normalizeStateName(state) == name {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// findStateName returns the first state name mentioned in text, as printed in
+// gstStates, or "" when there is none.
+func findStateName(text string) string {
+	padded := " " + normalizeStateName(text) + " "
+	best, at := "", -1
+	for _, name := range stateNames {
+		if i := strings.Index(padded, " "+name+" "); i >= 0 && (at < 0 || i < at) {
+			best, at = name, i
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	code, _ := stateCodeByName(best)
+	return gstStates[code]
+}
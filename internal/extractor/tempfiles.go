@@ -0,0 +1,42 @@
+package extractor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Patterns of the temporary files and directories an extraction creates in
+// os.TempDir, removed again when it ends.
+const (
+	tempPDFPattern      = "invoice-*.pdf"
+	tempAnnotatePattern = "invoice-annotate-*"
+)
+
+// SweepTempFiles removes the temporary PDFs and annotation directories older
+// than age, left behind by extractions whose process was killed before it
+// could clean up. Age keeps the files of extractions still running in another
+// process sharing the temp directory. It returns how many entries were removed,
+// and the errors of those that could not be.
+func SweepTempFiles(age time.Duration) (int, error) {
+	cutoff := time.Now().Add(-age)
+	var removed int
+	var errs []error
+	for _, pattern := range []string{tempPDFPattern, tempAnnotatePattern} {
+		// The patterns are constant and valid, so Glob cannot fail.
+		paths, _ := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+		for _, path := range paths {
+			info, err := os.Lstat(path)
+			if err != nil || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			if err := os.RemoveAll(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,16 @@
+package extractor
+
+import "unicode/utf8"
+
+// truncateText cuts text to at most limit bytes without splitting a character.
+// A limit of zero or less leaves text untouched. It reports whether text was cut.
+func truncateText(text string, limit int) (string, bool) {
+	if limit <= 0 || len(text) <= limit {
+		return text, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut], true
+}
@@ -0,0 +1,27 @@
+package extractor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"time"
+)
+
+// withTimeout bounds ctx by timeout or, when that is zero, by Config.Timeout,
+// when one is set.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout = cmp.Or(timeout, activeConfig().Timeout)
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutError reports err as ErrExtractionTimeout, keeping its stage, when the deadline
+// of ctx is what made it fail, so a killed script is not mistaken for a broken one.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return &StageError{Stage: cmp.Or(FailureStage(err), StagePython), Err: ErrExtractionTimeout}
+}
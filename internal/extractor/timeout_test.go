@@ -0,0 +1,25 @@
+package extractor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), time.Hour)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) <= activeConfig().Timeout {
+		t.Errorf("withTimeout(ctx, 1h) deadline = %v, %v, want about an hour away", deadline, ok)
+	}
+
+	ctx, cancel = withTimeout(context.Background(), 0)
+	defer cancel()
+	deadline, ok = ctx.Deadline()
+	if want := activeConfig().Timeout > 0; ok != want {
+		t.Errorf("withTimeout(ctx, 0) has deadline %v, want one only when Config.Timeout is set", deadline)
+	} else if ok && time.Until(deadline) > activeConfig().Timeout {
+		t.Errorf("withTimeout(ctx, 0) deadline %v is beyond Config.Timeout %v", deadline, activeConfig().Timeout)
+	}
+}
@@ -0,0 +1,211 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// reAmount matches a printed amount with at least two decimals, including the
+// negative forms understood by parseAmount. Extra decimals are captured rather
+// than cut off so applyPrecision can flag them.
+var reAmount = regexp.MustCompile(`\(?-?[\d,]*\d\.\d{2,}\)?`)
+
+// reSpaceGrouped matches an amount whose thousands are separated by plain
+// spaces, in Western ("123 456.00", "1 234 567.00") or Indian ("12 34 567.00")
+// grouping. The amount must not follow a digit or separator, so "10.00 234.00"
+// stays two amounts. A short leading group needs at least two full groups
+// after it, so a quantity column before the amount, as in "5 900.00", is not
+// read as part of it. Group 1 is what precedes the amount and group 2 the
+// amount.
+var reSpaceGrouped = regexp.MustCompile(`(^|[^\d.,])(\d{3}(?: \d{3})+\.\d{2,}|\d{1,3}(?: \d{3}){2,}\.\d{2,}|\d{1,2}(?: \d{2})+ \d{3}\.\d{2,})`)
+
+// rePrefixedAmount matches an amount printed after a currency code or symbol,
+// with or without decimals, as in "₹1,180" or "INR 1180.00". Group 1 is the
+// amount.
+var rePrefixedAmount = regexp.MustCompile(`(?:\b(?:` + strings.Join(currencyCodes, "|") + `)|` + currencySymbolPattern + `)\s*(\(?-?\d(?:[\d,]*\d)?(?:\.\d+)?\)?)`)
+
+// reNegativeCurrency matches a minus sign and a currency code or symbol at the
+// end of the text before an amount, as in "-₹50.00" or "-INR 50.00", where the
+// sign is printed ahead of the currency rather than of the digits.
+var reNegativeCurrency = regexp.MustCompile(`-\s*(?:(?:` + strings.Join(currencyCodes, "|") + `)|` + currencySymbolPattern + `)\s*$`)
+
+// totalLineAmounts returns the amounts printed on the rest of a total line, as
+// printed. Amounts are located on a copy whose space-separated thousands are
+// written with commas, since a space would otherwise split the amount in two;
+// the copy has the same length, so each amount is then cut from rest itself
+// and keeps its spaces, which parseAmount reads. Spaces are only read as
+// grouping on a line whose amounts all use it: next to an amount printed with
+// commas or without grouping, as in "2 180.00 1,180.00", a space separates
+// columns. A minus sign printed before a
+// currency code or symbol is kept with the amount. Amounts need two decimals,
+// as elsewhere, unless they follow a currency code or symbol: a total line
+// printed as "Total ₹1,180" has no other amount to mistake it for.
+func totalLineAmounts(rest string) []string {
+	scan := []byte(rest)
+	if grouped := reSpaceGrouped.FindAllStringSubmatchIndex(rest, -1); spaceGroupedOnly(rest, grouped) {
+		for _, m := range grouped {
+			for i := m[4]; i < m[5]; i++ {
+				if scan[i] == ' ' {
+					scan[i] = ','
+				}
+			}
+		}
+	}
+	locs := reAmount.FindAllIndex(scan, -1)
+	if len(locs) == 0 {
+		for _, m := range rePrefixedAmount.FindAllSubmatchIndex(scan, -1) {
+			locs = append(locs, m[2:4])
+		}
+	}
+	var amounts []string
+	for _, loc := range locs {
+		amount := rest[loc[0]:loc[1]]
+		if reNegativeCurrency.MatchString(rest[:loc[0]]) && !strings.HasPrefix(amount, "-") {
+			amount = "-" + amount
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
+
+// spaceGroupedOnly reports whether every amount on rest lies within one of the
+// space-grouped amounts matched by reSpaceGrouped at grouped.
+func spaceGroupedOnly(rest string, grouped [][]int) bool {
+	if len(grouped) == 0 {
+		return false
+	}
+	for _, loc := range reAmount.FindAllStringIndex(rest, -1) {
+		if !slices.ContainsFunc(grouped, func(m []int) bool { return m[4] <= loc[0] && loc[1] <= m[5] }) {
+			return false
+		}
+	}
+	return true
+}
+
+// totalLabelPattern compiles a total label such as "Grand Total" into a pattern
+// that matches a whole line containing it, capturing the rest of the line.
+// Words may be separated by any whitespace and case is ignored.
+func totalLabelPattern(label string) (*regexp.Regexp, error) {
+	words := strings.Fields(label)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.Compile(`(?im)^.*?\b` + strings.Join(words, `\s+`) + `\b\s*:?(.*)$`)
+}
+
+// findTotalLine locates the line holding the document total. Labels are tried in
+// order, most specific first; for the first label found, its last occurrence that
+// carries an amount wins, since line totals precede the grand total.
+// It returns the matching label pattern, the whole line and the amounts on it,
+// as read by totalLineAmounts.
+func findTotalLine(text string, labels []*regexp.Regexp) (*regexp.Regexp, string, []string) {
+	for _, re := range labels {
+		matches := re.FindAllStringSubmatch(text, -1)
+		for i := len(matches) - 1; i >= 0; i-- {
+			if amounts := totalLineAmounts(matches[i][1]); len(amounts) > 0 {
+				return re, matches[i][0], amounts
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// parseAmounts extracts the tax and total amounts, their numeric values and the
+// currency from the total line of text, and the GST components, then parses the
+// line items and reconciles their tax against the document's. DocumentType must
+// already be set, since it decides the sign of the values.
+func (d *InvoiceDetails) parseAmounts(text string) {
+	// When the total line carries several amounts the last is the total and
+	// the one before it the tax. A line with a single amount carries only the
+	// total; the tax is then read from the GST components below.
+	if re, line, amounts := findTotalLine(text, activeConfig().totalLabels); re != nil {
+		d.TotalAmount = amounts[len(amounts)-1]
+		if len(amounts) > 1 {
+			d.TaxAmount = amounts[len(amounts)-2]
+		}
+		d.Currency = detectCurrencyCode(line)
+		d.recordMatch("tax_amount", re, d.TaxAmount)
+		d.recordMatch("total_amount", re, d.TotalAmount)
+	}
+	d.parseGSTSplit(text)
+	d.TaxAmountValue = d.applyPrecision("tax_amount", d.TaxAmount,
+		signedAmount(d.TaxAmount, d.DocumentType))
+	d.TotalAmountValue = d.applyPrecision("total_amount", d.TotalAmount,
+		signedAmount(d.TotalAmount, d.DocumentType))
+
+	d.LineItems = parseLineItems(text)
+	reconcileLineTax(d)
+}
+
+// Totals is the amounts-only result of ExtractTotals.
+type Totals struct {
+	DocumentType     string    `json:"document_type"`
+	TaxAmount        string    `json:"tax_amount"`
+	TotalAmount      string    `json:"total_amount"`
+	CGST             string    `json:"cgst"`
+	SGST             string    `json:"sgst"`
+	IGST             string    `json:"igst"`
+	IsInterState     bool      `json:"is_inter_state"`
+	TaxAmountValue   float64   `json:"tax_amount_value"`
+	TotalAmountValue float64   `json:"total_amount_value"`
+	Currency         string    `json:"currency"`
+	Warnings         []Warning `json:"warnings,omitempty"`
+}
+
+// ExtractTotals reads only the amounts of an invoice. It runs the single 'simple'
+// text pass the amounts are parsed from, skipping the column layout needed for
+// the billing block, so it is roughly twice as fast as ExtractDetails. The amounts
+// go through the same precision and reconciliation checks.
+func ExtractTotals(file io.Reader) (*Totals, error) {
+	return ExtractTotalsContext(context.Background(), file)
+}
+
+// ExtractTotalsContext behaves like ExtractTotals but stops when ctx is done,
+// as ExtractDetailsContext does.
+func ExtractTotalsContext(ctx context.Context, file io.Reader) (*Totals, error) {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, file); err != nil {
+		return nil, fmt.Errorf("failed to buffer pdf content: %w", err)
+	}
+	if err := checkPageCount([][]byte{buf.Bytes()}); err != nil {
+		return nil, &StageError{Stage: StageRead, Err: err}
+	}
+	ctx, cancel := withTimeout(ctx, 0)
+	defer cancel()
+	pt, err := runPass(ctx, buf.Bytes(), textPass{mode: "simple"})
+	if err != nil {
+		return nil, timeoutError(ctx, &StageError{Stage: StagePython + "/simple", Err: err})
+	}
+	text := pt.text
+
+	d := &InvoiceDetails{}
+	text, cut := truncateText(text, activeConfig().MaxTextSize)
+	if cut {
+		d.warn(SeverityWarning, WarnTextTruncated, "simple text exceeded %d bytes and was truncated; fields past that point were not parsed", activeConfig().MaxTextSize)
+	}
+	text = normalizeNumerals(text)
+
+	d.DocumentType = detectDocumentType(text)
+	d.CompositionScheme = reComposition.MatchString(text)
+	d.parseAmounts(text)
+	d.reportChecks(false)
+
+	return &Totals{
+		DocumentType:     d.DocumentType,
+		TaxAmount:        d.TaxAmount,
+		TotalAmount:      d.TotalAmount,
+		CGST:             d.CGST,
+		SGST:             d.SGST,
+		IGST:             d.IGST,
+		IsInterState:     d.IsInterState,
+		TaxAmountValue:   d.TaxAmountValue,
+		TotalAmountValue: d.TotalAmountValue,
+		Currency:         d.Currency,
+		Warnings:         d.Warnings,
+	}, nil
+}
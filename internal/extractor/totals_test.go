@@ -0,0 +1,172 @@
+package extractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTotalLineAmounts(t *testing.T) {
+	tests := []struct {
+		name string
+		rest string
+		want []string
+	}{
+		{"tax and total", ": 180.00 1,180.00", []string{"180.00", "1,180.00"}},
+		{"adjacent amounts stay apart", " 10.00 234.00", []string{"10.00", "234.00"}},
+		{"western space grouping", " 1 234 567.89", []string{"1 234 567.89"}},
+		{"indian space grouping", " 12 34 567.00", []string{"12 34 567.00"}},
+		{"western space grouping of one group", " 123 456.00", []string{"123 456.00"}},
+		{"indian space grouping of a lakh", " 1 23 456.00", []string{"1 23 456.00"}},
+		{"no space grouping next to a decimal amount", " 180.00 1 180.00", []string{"180.00", "180.00"}},
+		{"quantity before tax and total", " 2 180.00 1,180.00", []string{"180.00", "1,180.00"}},
+		{"quantity before total", " 5 900.00", []string{"900.00"}},
+		{"rupee symbol", " ₹1,180.00", []string{"1,180.00"}},
+		{"rupee symbol without decimals", " ₹ 1,180", []string{"1,180"}},
+		{"dollar without decimals", " $1180", []string{"1180"}},
+		{"currency code without decimals", " INR 1180", []string{"1180"}},
+		{"rs prefix", ": Rs.1,180.00", []string{"1,180.00"}},
+		{"minus before rupee symbol", " -₹50.00", []string{"-50.00"}},
+		{"minus before dollar", " -$50.00", []string{"-50.00"}},
+		{"minus before currency code", " -INR 1,180", []string{"-1,180"}},
+		{"minus after symbol", " ₹-50.00", []string{"-50.00"}},
+		{"accounting negative", " (1,180.00)", []string{"(1,180.00)"}},
+		{"no amount", " as per annexure", nil},
+		{"whole number without currency", " 1180", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := totalLineAmounts(tt.rest); !slices.Equal(got, tt.want) {
+				t.Errorf("totalLineAmounts(%q) = %q, want %q", tt.rest, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAmountSpaceGrouped(t *testing.T) {
+	tests := []struct {
+		printed string
+		want    float64
+	}{
+		{"1 234 567.89", 1234567.89},
+		{"12 34 567.00", 1234567},
+		{"-50.00", -50},
+		{"-1 180.00", -1180},
+	}
+	for _, tt := range tests {
+		if got, ok := parseAmount(tt.printed); !ok || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.printed, got, ok, tt.want)
+		}
+		if !validGrouping(tt.printed, GroupingAny) {
+			t.Errorf("validGrouping(%q) = false", tt.printed)
+		}
+	}
+	if validGrouping("1 23 4567.00", GroupingAny) {
+		t.Error(`validGrouping("1 23 4567.00") = true`)
+	}
+}
+
+func TestFindTotalLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		label string
+		want  []string
+	}{
+		{
+			name:  "grand total preferred over a line total",
+			text:  "Widget 2 500.00 1,000.00\nTotal 1,000.00\nCGST 90.00\nGrand Total 180.00 1,180.00",
+			label: "Grand Total",
+			want:  []string{"180.00", "1,180.00"},
+		},
+		{
+			name:  "grand total printed before the plain total",
+			text:  "Grand Total: 1,180.00\nTotal 1,000.00",
+			label: "Grand Total",
+			want:  []string{"1,180.00"},
+		},
+		{
+			name:  "invoice total",
+			text:  "Invoice Total 2,360.00",
+			label: "Invoice Total",
+			want:  []string{"2,360.00"},
+		},
+		{
+			name:  "total amount",
+			text:  "Total Amount: 590.00",
+			label: "Total Amount",
+			want:  []string{"590.00"},
+		},
+		{
+			name:  "net amount",
+			text:  "Net Amount 4,720.00",
+			label: "Net Amount",
+			want:  []string{"4,720.00"},
+		},
+		{
+			name:  "amount payable",
+			text:  "Sub Total 1,000.00\nAmount Payable : 1,180.00",
+			label: "Amount Payable",
+			want:  []string{"1,180.00"},
+		},
+		{
+			name:  "mixed case and spacing",
+			text:  "GRAND   total:   1,180.00\nTotal 1,000.00",
+			label: "Grand Total",
+			want:  []string{"1,180.00"},
+		},
+		{
+			name:  "label split across a tab",
+			text:  "amount\tpayable 99.00",
+			label: "Amount Payable",
+			want:  []string{"99.00"},
+		},
+		{
+			name:  "last occurrence with an amount wins",
+			text:  "Total 100.00\nTotal 200.00\nTotal as per annexure",
+			label: "Total",
+			want:  []string{"200.00"},
+		},
+		{
+			name:  "quantity column before tax and total",
+			text:  "TOTAL 2 180.00 1,180.00",
+			label: "Total",
+			want:  []string{"180.00", "1,180.00"},
+		},
+		{
+			name:  "quantity column before total",
+			text:  "Total 5 900.00",
+			label: "Total",
+			want:  []string{"900.00"},
+		},
+		{
+			name:  "label inside a word is ignored",
+			text:  "Subtotal 1,000.00\nTotal 1,180.00",
+			label: "Total",
+			want:  []string{"1,180.00"},
+		},
+	}
+	labels := map[string]string{}
+	for i, label := range DefaultConfig().TotalLabels {
+		labels[activeConfig().totalLabels[i].String()] = label
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, _, amounts := findTotalLine(tt.text, activeConfig().totalLabels)
+			if re == nil {
+				t.Fatalf("findTotalLine(%q) found no total line", tt.text)
+			}
+			if got := labels[re.String()]; got != tt.label {
+				t.Errorf("findTotalLine(%q) matched label %q, want %q", tt.text, got, tt.label)
+			}
+			if !slices.Equal(amounts, tt.want) {
+				t.Errorf("findTotalLine(%q) amounts = %q, want %q", tt.text, amounts, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTotalLineNone(t *testing.T) {
+	if re, _, _ := findTotalLine("Subtotal 1,000.00\nTotal as per annexure", activeConfig().totalLabels); re != nil {
+		t.Errorf("findTotalLine matched %s on a document without a total amount", re)
+	}
+}
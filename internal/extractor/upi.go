@@ -0,0 +1,49 @@
+package extractor
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// reUPIPayment matches a UPI deep link such as "upi://pay?pa=shop@okaxis&pn=Shop".
+	reUPIPayment = regexp.MustCompile(`(?i)\bupi://pay\?\S+`)
+
+	// reUPIHandle matches a UPI virtual payment address ("name@bank"). Unlike an
+	// email address, the handle after the "@" has no dots, so the trailing
+	// character class keeps addresses like "a@gmail.com" out.
+	reUPIHandle = regexp.MustCompile(`(?:^|[^\w.\-@])([\w.\-]{2,256}@[A-Za-z][A-Za-z0-9]{1,63})(?:[^\w.\-@]|$)`)
+
+	// reValidUPIHandle is the format a UPI handle must have to be reported.
+	reValidUPIHandle = regexp.MustCompile(`^[A-Za-z0-9][\w.\-]{1,255}@[A-Za-z][A-Za-z0-9]{1,63}$`)
+)
+
+// findUPI returns the first UPI handle and UPI payment link in text. The handle
+// falls back to the payee address ("pa") of the payment link when the text
+// prints none of its own. Values that fail validation are not returned.
+func findUPI(text string) (handle, payment string) {
+	if link := reUPIPayment.FindString(text); link != "" {
+		payment = strings.TrimRight(link, ".,;)")
+	}
+
+	for _, match := range reUPIHandle.FindAllStringSubmatch(text, -1) {
+		if validUPIHandle(match[1]) {
+			return match[1], payment
+		}
+	}
+
+	if payment != "" {
+		if u, err := url.Parse(payment); err == nil {
+			if pa := u.Query().Get("pa"); validUPIHandle(pa) {
+				handle = pa
+			}
+		}
+	}
+	return handle, payment
+}
+
+// validUPIHandle reports whether s has the shape of a UPI virtual payment address.
+func validUPIHandle(s string) bool {
+	return reValidUPIHandle.MatchString(s)
+}
@@ -0,0 +1,146 @@
+package extractor
+
+import (
+	"fmt"
+	"math"
+)
+
+// ValidationFailure is one internal inconsistency found by InvoiceDetails.Validate.
+type ValidationFailure struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Names of the checks run by InvoiceDetails.Validate.
+const (
+	CheckLineItemsSubtotal = "line_items_subtotal"
+	CheckLineItemsTotal    = "line_items_total"
+	CheckLineItemsTax      = "line_items_tax"
+	CheckTotalBreakdown    = "total_breakdown"
+	CheckTaxComponents     = "tax_components"
+	CheckTaxWithinTotal    = "tax_within_total"
+	CheckStateCodeGSTIN    = "state_code_gstin"
+	CheckPayments          = "payments"
+)
+
+// roundOffTolerance is how far a computed total may drift from the printed one.
+// Indian invoices routinely round the payable amount to the nearest rupee.
+const roundOffTolerance = 0.50
+
+// mismatch is a reconciliation failure found while parsing, such as line item
+// taxes that don't add up to the document tax. It is reported once: as a
+// validation failure under check when the result is validated, otherwise as
+// an error warning with code.
+type mismatch struct {
+	check   string
+	code    string
+	message string
+}
+
+// mismatch records a reconciliation failure; see reportChecks.
+func (d *InvoiceDetails) mismatch(check, code, format string, args ...any) {
+	d.mismatches = append(d.mismatches, mismatch{check: check, code: code, message: fmt.Sprintf(format, args...)})
+}
+
+// reportChecks reports the reconciliation failures found while parsing. With
+// validate set, the result is validated and they are listed with the other
+// ValidationFailures; otherwise each becomes an error warning.
+func (d *InvoiceDetails) reportChecks(validate bool) {
+	if validate {
+		d.Validated = true
+		d.ValidationFailures = d.Validate()
+		return
+	}
+	for _, m := range d.mismatches {
+		d.warn(SeverityError, m.code, "%s", m.message)
+	}
+}
+
+// Validate checks the extracted values against each other and returns every
+// inconsistency found, or nil when they agree. It includes the reconciliation
+// failures found while parsing, such as line item taxes or payments that don't
+// add up. Checks whose inputs were not extracted are skipped, so a sparse
+// result validates cleanly.
+func (d *InvoiceDetails) Validate() []ValidationFailure {
+	var failures []ValidationFailure
+	for _, m := range d.mismatches {
+		failures = append(failures, ValidationFailure{Check: m.check, Message: m.message})
+	}
+	fail := func(check, format string, args ...any) {
+		failures = append(failures, ValidationFailure{Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	total := math.Abs(d.TotalAmountValue)
+	tax := math.Abs(d.TaxAmountValue)
+
+	if d.TotalAmount != "" && d.TaxAmount != "" && tax > total {
+		fail(CheckTaxWithinTotal, "tax %.2f exceeds the total %.2f", tax, total)
+	}
+
+	// Line amounts are printed either before or after their tax, so their sum,
+	// with or without the line taxes, must match the subtotal. Without a
+	// subtotal, it must match the total or the total less tax, give or take the
+	// round-off.
+	if sum, n := sumAmounts(d.LineItems, func(item LineItem) string { return item.Amount }); n > 0 {
+		lineTax, _ := sumAmounts(d.LineItems, func(item LineItem) string { return item.TaxAmount })
+		tolerance := 0.01 * float64(n)
+		switch {
+		case d.Subtotal != nil:
+			if math.Abs(sum-*d.Subtotal) > tolerance && math.Abs(sum-lineTax-*d.Subtotal) > tolerance {
+				fail(CheckLineItemsSubtotal, "line item amounts sum to %.2f but the subtotal is %.2f", sum, *d.Subtotal)
+			}
+		case d.TotalAmount != "":
+			tolerance += roundOffTolerance
+			if math.Abs(sum-total) > tolerance && math.Abs(sum+tax-total) > tolerance {
+				fail(CheckLineItemsTotal, "line item amounts sum to %.2f, which matches neither the total %.2f nor the total less tax %.2f", sum, total, total-tax)
+			}
+		}
+	}
+
+	// subtotal + tax + round-off - discount = total. An unprinted round-off
+	// may still have been applied, up to roundOffTolerance.
+	if expected, ok := d.breakdownTotal(); ok && d.TotalAmount != "" {
+		tolerance := 0.01
+		if d.RoundOff == nil {
+			tolerance = roundOffTolerance
+		}
+		if math.Abs(expected-total) > tolerance {
+			fail(CheckTotalBreakdown, "subtotal, tax, round-off and discount add up to %.2f but the total is %.2f", expected, total)
+		}
+	}
+
+	if d.TaxAmount != "" {
+		var sum float64
+		var found bool
+		for _, component := range []string{d.CGST, d.SGST, d.IGST} {
+			if v, ok := parseAmount(component); ok {
+				sum += math.Abs(v)
+				found = true
+			}
+		}
+		if found && math.Abs(sum-tax) > 0.01 {
+			fail(CheckTaxComponents, "CGST, SGST and IGST sum to %.2f but the document tax is %.2f", sum, tax)
+		}
+	}
+
+	// The first two digits of a GSTIN are the state code of its holder.
+	if d.StateCode != "" && len(d.GSTNOClient) >= 2 && d.GSTNOClient[:2] != d.StateCode {
+		fail(CheckStateCodeGSTIN, "state code %s does not match the client GSTIN %s", d.StateCode, d.GSTNOClient)
+	}
+
+	return failures
+}
+
+// sumAmounts adds up the absolute value of the amount field picks from each item,
+// returning the sum and how many items carried a parseable amount.
+func sumAmounts(items []LineItem, field func(LineItem) string) (float64, int) {
+	var sum float64
+	n := 0
+	for _, item := range items {
+		if v, ok := parseAmount(field(item)); ok {
+			sum += math.Abs(v)
+			n++
+		}
+	}
+	return sum, n
+}
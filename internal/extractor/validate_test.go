@@ -0,0 +1,220 @@
+package extractor
+
+import (
+	"slices"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestParseBreakdown(t *testing.T) {
+	tests := []struct {
+		name                         string
+		text                         string
+		subtotal, discount, roundOff *float64
+	}{
+		{
+			name:     "all three",
+			text:     "Sub Total 1,000.00\nDiscount 10% 100.00\nCGST 9% 81.00\nSGST 9% 81.00\nRound Off -0.00\nGrand Total 1,062.00",
+			subtotal: ptr(1000), discount: ptr(100), roundOff: ptr(0),
+		},
+		{
+			name:     "taxable value and negative round-off",
+			text:     "Taxable Value: 847.46\nIGST 18% 152.54\nRounding Off (-) 0.40\nTotal 999.60",
+			subtotal: ptr(847.46), roundOff: ptr(-0.40),
+		},
+		{
+			name:     "less round-off",
+			text:     "Subtotal 500.00\nLess: Round Off 0.25",
+			subtotal: ptr(500), roundOff: ptr(-0.25),
+		},
+		{
+			name:     "positive round-off",
+			text:     "Sub-Total 499.75\nRound Off 0.25",
+			subtotal: ptr(499.75), roundOff: ptr(0.25),
+		},
+		{
+			name: "headers without amounts",
+			text: "Description Qty Rate Discount Taxable Value\nWidget 1 100.00",
+		},
+	}
+	eq := func(a, b *float64) bool { return (a == nil) == (b == nil) && (a == nil || *a == *b) }
+	show := func(p *float64) any {
+		if p == nil {
+			return nil
+		}
+		return *p
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &InvoiceDetails{}
+			d.parseBreakdown(tt.text)
+			if !eq(d.Subtotal, tt.subtotal) || !eq(d.Discount, tt.discount) || !eq(d.RoundOff, tt.roundOff) {
+				t.Errorf("subtotal, discount, round-off = %v, %v, %v, want %v, %v, %v",
+					show(d.Subtotal), show(d.Discount), show(d.RoundOff),
+					show(tt.subtotal), show(tt.discount), show(tt.roundOff))
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	items := []LineItem{{Amount: "600.00"}, {Amount: "400.00"}}
+	tests := []struct {
+		name string
+		d    InvoiceDetails
+		want []string
+	}{
+		{
+			name: "consistent breakdown",
+			d: InvoiceDetails{
+				LineItems: items, Subtotal: ptr(1000), Discount: ptr(100), RoundOff: ptr(0.20),
+				TaxAmount: "161.80", TaxAmountValue: 161.80, CGST: "80.90", SGST: "80.90",
+				TotalAmount: "1,062.00", TotalAmountValue: 1062,
+			},
+		},
+		{
+			name: "breakdown does not add up",
+			d: InvoiceDetails{
+				LineItems: items, Subtotal: ptr(1000), Discount: ptr(100), RoundOff: ptr(0),
+				TaxAmount: "162.00", TaxAmountValue: 162,
+				TotalAmount: "1,162.00", TotalAmountValue: 1162,
+			},
+			want: []string{CheckTotalBreakdown},
+		},
+		{
+			name: "unprinted round-off within tolerance",
+			d: InvoiceDetails{
+				Subtotal: ptr(847.46), TaxAmount: "152.54", TaxAmountValue: 152.54,
+				TotalAmount: "1,000.40", TotalAmountValue: 1000.40,
+			},
+		},
+		{
+			name: "line items do not sum to the subtotal",
+			d: InvoiceDetails{
+				LineItems: items, Subtotal: ptr(1100),
+				TotalAmount: "1,100.00", TotalAmountValue: 1100,
+			},
+			want: []string{CheckLineItemsSubtotal},
+		},
+		{
+			name: "line items after tax match the subtotal",
+			d: InvoiceDetails{
+				LineItems: []LineItem{{Amount: "590.00", TaxAmount: "90.00"}}, Subtotal: ptr(500),
+				TaxAmount: "90.00", TaxAmountValue: 90,
+				TotalAmount: "590.00", TotalAmountValue: 590,
+			},
+		},
+		{
+			name: "without a subtotal, line items against the total",
+			d: InvoiceDetails{
+				LineItems: items, TaxAmount: "180.00", TaxAmountValue: 180,
+				TotalAmount: "1,500.00", TotalAmountValue: 1500,
+			},
+			want: []string{CheckLineItemsTotal},
+		},
+		{
+			name: "tax components",
+			d: InvoiceDetails{
+				CGST: "90.00", SGST: "80.00", TaxAmount: "180.00", TaxAmountValue: 180,
+				TotalAmount: "1,180.00", TotalAmountValue: 1180,
+			},
+			want: []string{CheckTaxComponents},
+		},
+		{
+			name: "tax within total and state code",
+			d: InvoiceDetails{
+				TaxAmount: "200.00", TaxAmountValue: 200, TotalAmount: "100.00", TotalAmountValue: 100,
+				StateCode: "29", GSTNOClient: "27AAPFU0939F1ZV",
+			},
+			want: []string{CheckTaxWithinTotal, CheckStateCodeGSTIN},
+		},
+		{
+			name: "sparse result",
+			d:    InvoiceDetails{InvoiceNumber: "INV-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range tt.d.Validate() {
+				got = append(got, f.Check)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() checks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A reconciliation mismatch is reported once: as a warning, or as a
+// validation failure when the result is validated.
+func TestReportChecksOnce(t *testing.T) {
+	newDetails := func() *InvoiceDetails {
+		d := &InvoiceDetails{
+			TaxAmount: "180.00", TaxAmountValue: 180, TotalAmount: "1,180.00", TotalAmountValue: 1180,
+			LineItems: []LineItem{{Amount: "590.00", TaxAmount: "90.00"}, {Amount: "590.00", TaxAmount: "80.00"}},
+		}
+		reconcileLineTax(d)
+		return d
+	}
+
+	d := newDetails()
+	d.reportChecks(false)
+	if len(d.Warnings) != 1 || d.Warnings[0].Code != WarnLineItemTaxMismatch {
+		t.Errorf("warnings = %+v, want one %s", d.Warnings, WarnLineItemTaxMismatch)
+	}
+	if d.Validated || d.ValidationFailures != nil {
+		t.Errorf("unvalidated result has validation failures %+v", d.ValidationFailures)
+	}
+
+	d = newDetails()
+	d.reportChecks(true)
+	if len(d.Warnings) != 0 {
+		t.Errorf("validated result also warned: %+v", d.Warnings)
+	}
+	if len(d.ValidationFailures) != 1 || d.ValidationFailures[0].Check != CheckLineItemsTax {
+		t.Errorf("validation failures = %+v, want one %s", d.ValidationFailures, CheckLineItemsTax)
+	}
+}
+
+func TestParsePayments(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		paid, due *float64
+		mismatch  bool
+	}{
+		{"partly paid", "Amount Paid: 500.00\nBalance Due: 680.00", ptr(500), ptr(680), false},
+		{"paid in full", "Balance Due 0.00", ptr(1180), ptr(0), false},
+		{"unpaid", "Amount Due: 1,180.00", ptr(0), ptr(1180), false},
+		{"advance only", "Less: Advance 200.00", ptr(200), ptr(980), false},
+		{"does not add up", "Amount Paid 500.00\nBalance Due 600.00", ptr(500), ptr(600), true},
+		{"not printed", "Total 1,180.00", nil, nil, false},
+	}
+	eq := func(a, b *float64) bool { return (a == nil) == (b == nil) && (a == nil || *a == *b) }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &InvoiceDetails{TotalAmount: "1,180.00", TotalAmountValue: 1180}
+			d.parsePayments(tt.text)
+			if !eq(d.AmountPaid, tt.paid) || !eq(d.BalanceDue, tt.due) {
+				t.Errorf("paid, due = %v, %v, want %v, %v", d.AmountPaid, d.BalanceDue, tt.paid, tt.due)
+			}
+			if got := len(d.mismatches) == 1 && d.mismatches[0].check == CheckPayments; got != tt.mismatch {
+				t.Errorf("payment mismatch recorded = %v, want %v (%+v)", got, tt.mismatch, d.mismatches)
+			}
+		})
+	}
+}
+
+// A payment mismatch is a validation failure or a warning, never both.
+func TestPaymentMismatchReportedOnce(t *testing.T) {
+	for _, validate := range []bool{false, true} {
+		d := &InvoiceDetails{TotalAmount: "1,180.00", TotalAmountValue: 1180}
+		d.parsePayments("Amount Paid 500.00\nBalance Due 600.00")
+		d.reportChecks(validate)
+		if n := len(d.Warnings) + len(d.ValidationFailures); n != 1 {
+			t.Errorf("validate=%v: reported %d times: warnings %+v, failures %+v", validate, n, d.Warnings, d.ValidationFailures)
+		}
+	}
+}
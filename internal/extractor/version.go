@@ -0,0 +1,64 @@
+package extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"runtime/debug"
+)
+
+// Version identifies the extractor build. Release builds set it with
+//
+//	go build -ldflags "-X github.com/avirsaha/SimpleInvoice/tree/stable-go/internal/extractor.Version=v1.2.0"
+//
+// Otherwise the VCS revision recorded by the Go toolchain is used, if any.
+var Version = ""
+
+// buildVersion returns Version, falling back to the VCS revision of the build
+// and then to "dev".
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision string
+		modified := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+		if revision != "" {
+			if modified {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+	return "dev"
+}
+
+// configVersion fingerprints the settings of cfg that shape extraction results
+// (labels, patterns, rules and limits), so results can be traced to the
+// settings that produced them. Equal settings give equal fingerprints. The
+// interpreter and script paths, timeout, cache size and worker count only
+// affect how a result is produced, so they are left out: moving the script or
+// resizing the cache does not change the version.
+func configVersion(cfg Config) string {
+	cfg.PythonPath, cfg.ScriptPath = "", ""
+	cfg.Timeout = 0
+	cfg.CacheSize = 0
+	cfg.PythonWorkers = 0
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,33 @@
+package extractor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigVersion(t *testing.T) {
+	base := DefaultConfig()
+	want := configVersion(base)
+
+	runtime := DefaultConfig()
+	runtime.PythonPath = "/opt/python3/bin/python3"
+	runtime.ScriptPath = "/srv/simpleinvoice/extract.py"
+	runtime.Timeout = 90 * time.Second
+	runtime.CacheSize = base.CacheSize + 100
+	runtime.PythonWorkers = base.PythonWorkers + 4
+	if got := configVersion(runtime); got != want {
+		t.Errorf("configVersion() = %q after changing paths, timeout, cache and workers, want %q", got, want)
+	}
+
+	parsing := DefaultConfig()
+	parsing.TotalLabels = append([]string{"Net Payable"}, base.TotalLabels...)
+	if got := configVersion(parsing); got == want {
+		t.Errorf("configVersion() = %q after changing TotalLabels, want a new version", got)
+	}
+
+	precision := DefaultConfig()
+	precision.AmountPrecision = base.AmountPrecision + 1
+	if got := configVersion(precision); got == want {
+		t.Errorf("configVersion() = %q after changing AmountPrecision, want a new version", got)
+	}
+}
@@ -0,0 +1,56 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// TableView is a display-oriented shaping of InvoiceDetails: the populated scalar
+// fields as a summary, in the order they should be shown, and the line items
+// as the rows of an items table.
+type TableView struct {
+	Summary  OrderedFields `json:"summary"`
+	Items    []LineItem    `json:"items"`
+	Warnings []Warning     `json:"warnings,omitempty"`
+}
+
+// OrderedFields is a list of [name, value] pairs that marshals as a JSON object
+// whose keys keep the list order.
+type OrderedFields [][2]string
+
+// MarshalJSON writes the pairs as one JSON object, in order.
+func (f OrderedFields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		for j, s := range field {
+			b, err := json.Marshal(s)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+			if j == 0 {
+				buf.WriteByte(':')
+			}
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// TableView returns d shaped for display. Summary values are rendered as text the
+// same way as on the annotated summary page; Items is never nil.
+func (d *InvoiceDetails) TableView() *TableView {
+	items := d.LineItems
+	if items == nil {
+		items = []LineItem{}
+	}
+	return &TableView{
+		Summary:  OrderedFields(summaryFields(d)),
+		Items:    items,
+		Warnings: d.Warnings,
+	}
+}
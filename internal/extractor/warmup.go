@@ -0,0 +1,44 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// warmupPDF is a one-page PDF with a single line of text, small enough to
+// extract in milliseconds once the interpreter is up.
+//
+//go:embed warmup.pdf
+var warmupPDF []byte
+
+// Warmup runs a trivial extraction through the Python backend so the interpreter,
+// its imported libraries and the OS file cache are hot before real traffic arrives.
+// It returns an error when the backend cannot extract the known sample text.
+func Warmup() error {
+	return WarmupContext(context.Background())
+}
+
+// WarmupContext behaves like Warmup but kills the extraction when ctx is done
+// or Config.Timeout elapses, as for any other extraction.
+func WarmupContext(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, 0)
+	defer cancel()
+	return timeoutError(ctx, CheckBackend(ctx))
+}
+
+// CheckBackend extracts the bundled sample PDF and reports an error unless the
+// known sample text comes back, proving the interpreter, the script and its
+// libraries all work. Cancelling ctx kills the extraction.
+func CheckBackend(ctx context.Context) error {
+	text, err := extractTextWithPython(ctx, bytes.NewReader(warmupPDF), "simple", nil, "")
+	if err != nil {
+		return fmt.Errorf("warmup extraction failed: %w", err)
+	}
+	if !strings.Contains(text, "WARMUP-0001") {
+		return fmt.Errorf("warmup extraction returned unexpected text %q", text)
+	}
+	return nil
+}
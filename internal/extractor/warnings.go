@@ -0,0 +1,65 @@
+package extractor
+
+import "fmt"
+
+// Warning severities, from least to most serious. Results carrying only
+// SeverityInfo warnings can usually be accepted without review.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Warning codes identify the kind of problem independently of the message text.
+const (
+	WarnMissingField         = "missing_field"
+	WarnAmountRounded        = "amount_rounded"
+	WarnMalformedAmount      = "malformed_amount"
+	WarnNotesTruncated       = "notes_truncated"
+	WarnTextTruncated        = "text_truncated"
+	WarnPartialResult        = "partial_result"
+	WarnLayoutConflict       = "layout_conflict"
+	WarnItemCountMismatch    = "item_count_mismatch"
+	WarnUnknownState         = "unknown_state"
+	WarnInvalidFormat        = "invalid_format"
+	WarnPlaceOfSupply        = "place_of_supply_mismatch"
+	WarnLineItemTaxMismatch  = "line_item_tax_mismatch"
+	WarnPaymentMismatch      = "payment_mismatch"
+	WarnExchangeRateMismatch = "exchange_rate_mismatch"
+	WarnOCRFallback          = "ocr_fallback"
+)
+
+// Warning is a non-fatal problem noticed while parsing.
+type Warning struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// warn records a non-fatal parsing problem on the result.
+func (d *InvoiceDetails) warn(severity, code, format string, args ...any) {
+	d.Warnings = append(d.Warnings, Warning{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// noteMissingFields adds an info warning for each optional field left empty.
+// Nothing is reported for a result that extracted nothing at all, where every
+// field is missing and the Extracted flag already says so.
+func (d *InvoiceDetails) noteMissingFields() {
+	if !d.Extracted {
+		return
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"order_number", d.OrderNumber},
+		{"order_date", d.OrderDate},
+		{"billing_address", d.BillingAddress},
+		{"gst_no_client", d.GSTNOClient},
+		{"hsn", d.HSN},
+	} {
+		if f.value == "" {
+			d.warn(SeverityInfo, WarnMissingField, "%s was not found", f.name)
+		}
+	}
+}
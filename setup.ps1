@@ -11,7 +11,7 @@ Copy-Item -Recurse -Force . $InstallDir
 Push-Location $InstallDir
 
 Write-Host "Building Go binary..."
-& go build -o $BinName cmd/server/main.go
+& go build -o $BinName ./cmd/server
 
 Write-Host "Setting up Python virtual environment..."
 Set-Location tools
@@ -0,0 +1,68 @@
+"""Append a summary page of extracted invoice fields to a PDF.
+
+Contract (called by the Go server):
+
+    python pdf_annotator.py <input.pdf> <fields.json> <output.pdf>
+
+fields.json holds a JSON array of [label, value] string pairs, in display order.
+On success the annotated copy is written to output.pdf and the exit code is 0;
+on failure a message is written to stderr and the exit code is non-zero.
+The input PDF is never modified.
+"""
+import json
+import os
+import sys
+import tempfile
+import textwrap
+
+import pypdfium2 as pdfium
+from PIL import Image, ImageDraw, ImageFont
+
+SCALE = 2  # Render at 144 DPI so the text stays crisp when printed.
+MARGIN = 48
+LINE_HEIGHT = 16
+WRAP_COLUMNS = 70
+
+def render_summary(fields, width, height, path):
+    image = Image.new("RGB", (int(width * SCALE), int(height * SCALE)), "white")
+    draw = ImageDraw.Draw(image)
+    title_font = ImageFont.load_default(size=18 * SCALE)
+    font = ImageFont.load_default(size=10 * SCALE)
+
+    y = MARGIN
+    draw.text((MARGIN * SCALE, y * SCALE), "Extracted invoice fields", fill="black", font=title_font)
+    y += LINE_HEIGHT * 2
+
+    for label, value in fields:
+        lines = textwrap.wrap(str(value), WRAP_COLUMNS) or [""]
+        draw.text((MARGIN * SCALE, y * SCALE), label, fill="dimgray", font=font)
+        for line in lines:
+            draw.text(((MARGIN + 150) * SCALE, y * SCALE), line, fill="black", font=font)
+            y += LINE_HEIGHT
+        if y > height - MARGIN:
+            break
+
+    image.save(path, "PDF", resolution=72 * SCALE)
+
+if __name__ == "__main__":
+    if len(sys.argv) != 4:
+        print("Usage: python pdf_annotator.py <input.pdf> <fields.json> <output.pdf>", file=sys.stderr)
+        sys.exit(2)
+
+    input_path, fields_path, output_path = sys.argv[1:]
+    with open(fields_path, encoding="utf-8") as f:
+        fields = json.load(f)
+
+    pdf = pdfium.PdfDocument(input_path)
+    width, height = pdf[len(pdf) - 1].get_size() if len(pdf) else (612, 792)
+
+    fd, summary_path = tempfile.mkstemp(suffix=".pdf")
+    os.close(fd)
+    try:
+        render_summary(fields, width, height, summary_path)
+        summary = pdfium.PdfDocument(summary_path)
+        pdf.import_pages(summary)
+        summary.close()
+        pdf.save(output_path)
+    finally:
+        os.remove(summary_path)
@@ -1,3 +1,5 @@
+import argparse
+import json
 import sys
 import pdfplumber
 
@@ -30,25 +32,103 @@ def extract_text_columns(page):
 
     return reconstruct(left_lines) + "\n\n" + reconstruct(right_lines)
 
-if __name__ == "__main__":
-    if len(sys.argv) < 2:
-        print("Usage: python pdf_text_extractor.py <file.pdf> [--mode=simple|columns]")
-        sys.exit(1)
+def extract_text_ocr(page):
+    # Imported lazily so text-only deployments don't need Tesseract installed.
+    import pytesseract
+    image = page.to_image(resolution=300).original
+    return pytesseract.image_to_string(image)
+
+def extract_text_pdfminer(pdf_path, indices):
+    # pdfminer.six is already installed as a dependency of pdfplumber.
+    from pdfminer.high_level import extract_text
+    return "\n\n".join(extract_text(pdf_path, page_numbers=[i]) for i in indices)
+
+def extract_text_pdfium(pdf_path, indices):
+    import pypdfium2 as pdfium
+    pdf = pdfium.PdfDocument(pdf_path)
+    try:
+        return "\n\n".join(pdf[i].get_textpage().get_text_range() for i in indices)
+    finally:
+        pdf.close()
+
+def parse_pages(value):
+    pages = []
+    for part in value.split(","):
+        number = int(part)
+        if number < 1:
+            raise argparse.ArgumentTypeError("page numbers start at 1")
+        pages.append(number)
+    return pages
+
+ENGINES = {
+    "pdfminer": extract_text_pdfminer,
+    "pdfium": extract_text_pdfium,
+}
+
+EXTRACTORS = {
+    "simple": extract_text_simple,
+    "columns": extract_text_columns,
+    "ocr": extract_text_ocr,
+}
 
-    pdf_path = sys.argv[1]
-    mode = "simple"
-    if len(sys.argv) > 2 and sys.argv[2].startswith("--mode="):
-        mode = sys.argv[2].split("=")[1]
+def extract(pdf_path, mode="simple", pages=None, engine="pdfplumber"):
+    if mode not in EXTRACTORS:
+        raise ValueError("unknown mode %r" % mode)
+    if engine not in ENGINES and engine != "pdfplumber":
+        raise ValueError("unknown engine %r" % engine)
+    if engine != "pdfplumber" and mode != "simple":
+        raise ValueError("engine %s only supports mode simple" % engine)
 
     with pdfplumber.open(pdf_path) as pdf:
         if len(pdf.pages) == 0:
-            print("")
-            sys.exit(0)
+            return ""
 
-        page = pdf.pages[-1]
-
-        if mode == "columns":
-            print(extract_text_columns(page))
+        if pages:
+            indices = [n - 1 for n in pages if 1 <= n <= len(pdf.pages)]
         else:
-            print(extract_text_simple(page))
+            indices = [len(pdf.pages) - 1]
+
+        if engine in ENGINES:
+            return ENGINES[engine](pdf_path, indices)
+        return "\n\n".join(EXTRACTORS[mode](pdf.pages[i]) for i in indices)
+
+def serve():
+    # Worker mode: one JSON request per line on stdin, one JSON reply per line
+    # on stdout, so the interpreter and libraries are loaded once.
+    for line in sys.stdin:
+        if not line.strip():
+            continue
+        try:
+            req = json.loads(line)
+            text = extract(req["pdf_path"], req.get("mode") or "simple",
+                           req.get("pages"), req.get("engine") or "pdfplumber")
+            reply = {"text": text}
+        except Exception as exc:
+            reply = {"error": "%s: %s" % (type(exc).__name__, exc)}
+        sys.stdout.write(json.dumps(reply) + "\n")
+        sys.stdout.flush()
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(
+        usage="python pdf_text_extractor.py <file.pdf> [--mode=simple|columns|ocr] [--pages=1,2]"
+              " [--engine=pdfplumber|pdfminer|pdfium]\n"
+              "       python pdf_text_extractor.py --serve")
+    parser.add_argument("pdf_path", nargs="?")
+    parser.add_argument("--mode", choices=["simple", "columns", "ocr"], default="simple")
+    parser.add_argument("--pages", type=parse_pages,
+                        help="1-based pages to process; defaults to the last page")
+    parser.add_argument("--engine", choices=["pdfplumber", "pdfminer", "pdfium"], default="pdfplumber",
+                        help="library used to read the text layer; only pdfplumber supports the columns and ocr modes")
+    parser.add_argument("--serve", action="store_true",
+                        help="read JSON requests {pdf_path, mode, pages, engine} from stdin, one per line,"
+                             " and answer each with a JSON line {text} or {error}")
+    args = parser.parse_args()
+    if args.serve:
+        serve()
+        sys.exit(0)
+    if args.pdf_path is None:
+        parser.error("the pdf path is required")
+    if args.engine != "pdfplumber" and args.mode != "simple":
+        parser.error("--engine=%s only supports --mode=simple" % args.engine)
 
+    print(extract(args.pdf_path, args.mode, args.pages, args.engine))